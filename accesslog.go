@@ -0,0 +1,334 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const clfDate = "02/Jan/2006:15:04:05 -0700"
+
+// rotatingFile is an io.Writer backed by a file that is rotated once it
+// exceeds a maximum size or age, and can be reopened on demand.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	rf.f = f
+	rf.size = info.Size()
+	rf.openedAt = time.Now()
+
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if rf.needsRotation(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+
+	return n, err
+}
+
+func (rf *rotatingFile) needsRotation(n int64) bool {
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+n > rf.maxSize {
+		return true
+	}
+
+	return rf.maxAge > 0 && time.Since(rf.openedAt) > rf.maxAge
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+
+	backup := rf.path + "." + time.Now().Format("20060102T150405.000")
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+
+	rf.pruneBackups()
+
+	return rf.open()
+}
+
+func (rf *rotatingFile) pruneBackups() {
+	if rf.maxBackups <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(rf.path + ".*")
+	if err != nil || len(backups) <= rf.maxBackups {
+		return
+	}
+
+	slices.Sort(backups)
+
+	for _, b := range backups[:len(backups)-rf.maxBackups] {
+		if err := os.Remove(b); err != nil {
+			slog.Warn("Failed to remove old access log", "path", b, "error", err)
+		}
+	}
+}
+
+// Reopen closes and reopens the file, for use with external log rotation.
+func (rf *rotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f != nil {
+		if err := rf.f.Close(); err != nil {
+			return err
+		}
+		rf.f = nil
+	}
+
+	return rf.open()
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		return nil
+	}
+
+	err := rf.f.Close()
+	rf.f = nil
+
+	return err
+}
+
+type accessLogger struct {
+	format string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newAccessLogger(ctx context.Context, cfg *Config) (*accessLogger, error) {
+	al := &accessLogger{
+		format: cfg.accessLogFormat,
+		w:      os.Stdout,
+	}
+
+	if cfg.accessLog == "-" {
+		return al, nil
+	}
+
+	rf, err := newRotatingFile(cfg.accessLog, cfg.accessLogMaxSize*1000*1000, cfg.accessLogMaxAge, cfg.accessLogMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	al.w = rf
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		defer rf.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := rf.Reopen(); err != nil {
+					slog.Error("Failed to reopen access log", "path", cfg.accessLog, "error", err)
+
+					continue
+				}
+				slog.Info("Reopened access log", "path", cfg.accessLog)
+			}
+		}
+	}()
+
+	return al, nil
+}
+
+type accessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Remote    string    `json:"remote_addr"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Duration  float64   `json:"duration_ms"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+func (al *accessLogger) log(e accessLogEntry) {
+	var line []byte
+
+	switch al.format {
+	case "json":
+		b, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		line = append(b, '\n')
+	default:
+		size := "-"
+		if e.Bytes > 0 {
+			size = strconv.FormatInt(e.Bytes, 10)
+		}
+
+		s := fmt.Sprintf("%s - - [%s] %s %d %s",
+			e.Remote,
+			e.Time.Format(clfDate),
+			strconv.Quote(e.Method+" "+e.URI+" "+e.Proto),
+			e.Status,
+			size,
+		)
+
+		if al.format == "combined" {
+			s += " " + strconv.Quote(orDash(e.Referer)) + " " + strconv.Quote(orDash(e.UserAgent))
+		}
+
+		line = []byte(s + "\n")
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	_, _ = al.w.Write(line)
+}
+
+func (al *accessLogger) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		al.log(accessLogEntry{
+			Time:      startTime,
+			Remote:    remoteHost(r),
+			Method:    r.Method,
+			URI:       redactURI(r.RequestURI),
+			Proto:     r.Proto,
+			Status:    status,
+			Bytes:     rec.written,
+			Duration:  float64(time.Since(startTime).Microseconds()) / 1000,
+			Referer:   redactURI(r.Referer()),
+			UserAgent: r.UserAgent(),
+		})
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// redactedParams are query parameters carrying signed tokens. Anyone who can
+// read the access log could otherwise use them to join a game, pair a display
+// or take over as moderator.
+var redactedParams = []string{inviteParam, displayParam}
+
+// redactURI replaces the values of redactedParams in a URI, leaving the rest
+// of it as sent.
+func redactURI(uri string) string {
+	base, query, ok := strings.Cut(uri, "?")
+	if !ok {
+		return uri
+	}
+
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+
+		if name, err := url.QueryUnescape(key); err == nil && slices.Contains(redactedParams, name) {
+			pairs[i] = key + "=REDACTED"
+		}
+	}
+
+	uri = base + "?" + strings.Join(pairs, "&")
+	if hasFragment {
+		uri += "#" + fragment
+	}
+
+	return uri
+}
@@ -0,0 +1,31 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "testing"
+
+func TestRedactURI(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"/celebrity", "/celebrity"},
+		{"/healthz?format=json", "/healthz?format=json"},
+		{"/celebrity?invite=abc.def", "/celebrity?invite=REDACTED"},
+		{"/celebrity/qr?invite=abc&size=256", "/celebrity/qr?invite=REDACTED&size=256"},
+		{"/celebrity/ws?game=X1&display=tok", "/celebrity/ws?game=X1&display=REDACTED"},
+		{"/celebrity?%69nvite=abc", "/celebrity?%69nvite=REDACTED"},
+		{"/celebrity?invite", "/celebrity?invite=REDACTED"},
+		{"https://example.com/celebrity?invite=abc#top", "https://example.com/celebrity?invite=REDACTED#top"},
+		{"/celebrity?invitee=abc", "/celebrity?invitee=abc"},
+	}
+
+	for _, tt := range tests {
+		if got := redactURI(tt.in); got != tt.want {
+			t.Errorf("redactURI(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
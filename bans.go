@@ -0,0 +1,333 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errBannedFromGame   = errors.New("You have been banned from this game.")
+	errBannedFromServer = errors.New("You have been banned from this server.")
+)
+
+// Ban blocks a player cookie, an address or range, or both, from a single
+// game or, if Game is empty, the whole server.
+type Ban struct {
+	ID        string    `json:"id"`
+	Game      string    `json:"game,omitempty"` // game type and ID, e.g. celebrity/AbCd1234
+	PlayerID  string    `json:"player_id,omitempty"`
+	IP        string    `json:"ip,omitempty"` // address or cidr range
+	Reason    string    `json:"reason,omitempty"`
+	Source    string    `json:"source"` // "moderator", "admin", or "config"
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+
+	prefix netip.Prefix
+}
+
+func (b *Ban) expired(now time.Time) bool {
+	return !b.ExpiresAt.IsZero() && !now.Before(b.ExpiresAt)
+}
+
+func (b *Ban) matches(game, playerID string, addr netip.Addr) bool {
+	if b.Game != "" && b.Game != game {
+		return false
+	}
+
+	if b.PlayerID != "" && b.PlayerID == playerID {
+		return true
+	}
+
+	return b.prefix.IsValid() && addr.IsValid() && b.prefix.Contains(addr.Unmap())
+}
+
+// parseBanIP accepts an address or cidr range.
+func parseBanIP(s string) (netip.Prefix, error) {
+	prefixes, err := parseTrustedProxies([]string{s})
+	if err != nil || len(prefixes) != 1 {
+		return netip.Prefix{}, fmt.Errorf("invalid ban ip (must be an ip address or cidr range): %q", s)
+	}
+
+	return prefixes[0], nil
+}
+
+// newBan validates a ban and fills in its ID and creation time.
+func newBan(b Ban) (*Ban, error) {
+	if b.PlayerID == "" && b.IP == "" {
+		return nil, errors.New("a ban requires a player id, an ip, or both")
+	}
+
+	if b.IP != "" {
+		prefix, err := parseBanIP(b.IP)
+		if err != nil {
+			return nil, err
+		}
+		b.prefix = prefix
+		b.IP = prefix.String()
+		if prefix.IsSingleIP() {
+			b.IP = prefix.Addr().String()
+		}
+	}
+
+	if b.ID == "" {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		b.ID = hex.EncodeToString(buf)
+	}
+
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+
+	return &b, nil
+}
+
+// BanList holds the active bans. Bans from the config file are replaced
+// whenever it is reloaded, while all others are persisted to the store.
+type BanList struct {
+	mu         sync.RWMutex
+	bans       []*Ban
+	configured []*Ban
+	store      *Store
+}
+
+var bans = &BanList{}
+
+// load restores persisted bans from the store, which is then used for any
+// later changes.
+func (bl *BanList) load(store *Store) error {
+	data, err := store.load()
+	if err != nil {
+		return err
+	}
+
+	restored := make([]*Ban, 0, len(data.Bans))
+	for _, b := range data.Bans {
+		ban, err := newBan(*b)
+		if err != nil {
+			slog.Warn("Ignoring invalid ban in store", "id", b.ID, "error", err)
+
+			continue
+		}
+		restored = append(restored, ban)
+	}
+
+	bl.mu.Lock()
+	bl.store = store
+	bl.bans = restored
+	bl.mu.Unlock()
+
+	return nil
+}
+
+// setConfigured replaces the bans from the config file, reporting whether
+// they changed.
+func (bl *BanList) setConfigured(list []*Ban) bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	changed := !slices.EqualFunc(bl.configured, list, func(a, b *Ban) bool {
+		return a.PlayerID == b.PlayerID && a.IP == b.IP && a.Reason == b.Reason && a.ExpiresAt.Equal(b.ExpiresAt)
+	})
+
+	bl.configured = list
+
+	return changed
+}
+
+// check returns an error describing the first ban which applies to a
+// player connecting to a game, if any.
+func (bl *BanList) check(game, playerID string, addr netip.Addr) error {
+	now := time.Now()
+
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	for _, list := range [][]*Ban{bl.configured, bl.bans} {
+		for _, b := range list {
+			if b.expired(now) || !b.matches(game, playerID, addr) {
+				continue
+			}
+
+			metrics.bannedClients.inc(b.scope())
+
+			if b.Game != "" {
+				return errBannedFromGame
+			}
+
+			return errBannedFromServer
+		}
+	}
+
+	return nil
+}
+
+func (b *Ban) scope() string {
+	if b.Game != "" {
+		return "game"
+	}
+
+	return "server"
+}
+
+func (bl *BanList) add(b *Ban) error {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	bl.bans = append(bl.bans, b)
+
+	return bl.saveLocked()
+}
+
+// remove deletes a ban by ID, reporting whether it was found. Bans from the
+// config file cannot be removed.
+func (bl *BanList) remove(id string) (bool, error) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	i := slices.IndexFunc(bl.bans, func(b *Ban) bool { return b.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+
+	bl.bans = slices.Delete(bl.bans, i, i+1)
+
+	return true, bl.saveLocked()
+}
+
+// list returns all unexpired bans, including those from the config file.
+func (bl *BanList) list() []*Ban {
+	now := time.Now()
+
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	out := make([]*Ban, 0, len(bl.configured)+len(bl.bans))
+	for _, b := range slices.Concat(bl.configured, bl.bans) {
+		if !b.expired(now) {
+			out = append(out, b)
+		}
+	}
+
+	return out
+}
+
+// prune discards expired bans.
+func (bl *BanList) prune() {
+	now := time.Now()
+
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	n := len(bl.bans)
+	bl.bans = slices.DeleteFunc(bl.bans, func(b *Ban) bool { return b.expired(now) })
+
+	if len(bl.bans) != n {
+		if err := bl.saveLocked(); err != nil {
+			slog.Error("Failed to save bans", "error", err)
+		}
+	}
+}
+
+// banPruneInterval controls how often expired bans are discarded.
+const banPruneInterval = time.Minute
+
+func (bl *BanList) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(banPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			bl.prune()
+		}
+	}
+}
+
+func (bl *BanList) saveLocked() error {
+	return bl.store.save(&storeData{Bans: bl.bans})
+}
+
+// readBans parses the bans section of the config file.
+func readBans(raw any) ([]*Ban, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	var entries []any
+
+	switch list := raw.(type) {
+	case []any:
+		entries = list
+	case []map[string]any: // toml arrays of tables
+		for _, entry := range list {
+			entries = append(entries, entry)
+		}
+	default:
+		return nil, errors.New("invalid bans section in config file: must be a list")
+	}
+
+	list := make([]*Ban, 0, len(entries))
+
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid ban %d in config file: must be a map", i+1)
+		}
+
+		b := Ban{
+			ID:     fmt.Sprintf("config-%d", i+1),
+			Source: "config",
+		}
+
+		for key, value := range fields {
+			switch strings.ToLower(key) {
+			case "player-id", "player_id":
+				b.PlayerID = fmt.Sprint(value)
+			case "ip":
+				b.IP = fmt.Sprint(value)
+			case "reason":
+				b.Reason = fmt.Sprint(value)
+			case "expires":
+				switch t := value.(type) {
+				case time.Time:
+					b.ExpiresAt = t
+				default:
+					expires, err := time.Parse(time.RFC3339, fmt.Sprint(t))
+					if err != nil {
+						return nil, fmt.Errorf("invalid expiry for ban %d in config file (must be rfc 3339): %w", i+1, err)
+					}
+					b.ExpiresAt = expires
+				}
+			default:
+				return nil, fmt.Errorf("unknown option for ban %d in config file: %q", i+1, key)
+			}
+		}
+
+		ban, err := newBan(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ban %d in config file: %w", i+1, err)
+		}
+
+		list = append(list, ban)
+	}
+
+	return list, nil
+}
@@ -15,6 +15,10 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 // - Moderator can see username ↔ celebrity mapping
 // - Moderator can lock/unlock lobby (no new players when locked)
 // - Moderator can kick players
+// - Moderator rights can be transferred, and shared with co-moderators
+// - Moderator rights pass to someone else if the host is gone for too long
+// - Spectators follow the game without playing, and can be promoted before it starts
+// - Shared displays (/path/:gameid/display) show public state on a TV once paired by a moderator
 // - Players identified by cookie (playerID)
 // - Duplicate usernames and celebrity names prevented across players
 // - Collision messages sent only to the offending client
@@ -25,18 +29,31 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 // - Game ends when only one player remains in
 // - Teams are tracked as guessed players join the guesser's team
 // - In-browser QR button to share the current session, backed by go-qrcode
+// - Signed invite links (single-use, expiring, or team-assigning) and a moderator reclaim link
 
 package main
 
 import (
+	"cmp"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	_ "embed"
 	"encoding/hex"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 	"github.com/julienschmidt/httprouter"
@@ -48,15 +65,25 @@ type Player struct {
 	PlayerID  string
 	Username  string
 	Celebrity string
+	Team      string // assigned by an invite, if any
 }
 
 // Messages coming from clients
 type ClientMessage struct {
-	Type           string `json:"type"`                      // "join", "lock_lobby", "kick", "start_game", "restart_game", "guess"
+	Type           string `json:"type"`                      // "join", "spectate", "lock_lobby", "kick", "approve_join", "reject_join", "set_pin", "create_invite", "create_reclaim_link", "transfer_moderator", "add_co_moderator", "remove_co_moderator", "promote_spectator", "pair_display", "start_game", "restart_game", "guess", "vote_moderator"
 	Username       string `json:"username,omitempty"`        // join
 	Celebrity      string `json:"celebrity,omitempty"`       // join / guess
 	Lock           *bool  `json:"lock,omitempty"`            // lock_lobby
-	TargetUsername string `json:"target_username,omitempty"` // kick / guess
+	TargetUsername string `json:"target_username,omitempty"` // kick / approve_join / reject_join / guess / vote_moderator
+	PIN            string `json:"pin,omitempty"`             // join / set_pin
+	Ban            bool   `json:"ban,omitempty"`             // kick: also ban the player's cookie
+	BanIP          bool   `json:"ban_ip,omitempty"`          // kick: also ban the player's ip addresses
+	Role           string `json:"role,omitempty"`            // create_invite
+	Team           string `json:"team,omitempty"`            // create_invite
+	SingleUse      bool   `json:"single_use,omitempty"`      // create_invite
+	Duration       string `json:"duration,omitempty"`        // create_invite: go duration string; never expires if empty
+	Target         string `json:"target,omitempty"`          // transfer_moderator / add_co_moderator / remove_co_moderator / promote_spectator: device code
+	Code           string `json:"code,omitempty"`            // pair_display: code shown on the display
 }
 
 // Messages sent to clients
@@ -65,9 +92,10 @@ type CelebrityListMessage struct {
 	Celebrities []string `json:"celebrities"` // list of celebrity names
 }
 
-// Sent to a single client when there's a username/celebrity collision
+// Sent to a single client when there's a username/celebrity collision, or
+// when one is invalid
 type CollisionMessage struct {
-	Type    string `json:"type"`    // "collision"
+	Type    string `json:"type"`    // "collision" or "invalid_field"
 	Field   string `json:"field"`   // "username" or "celebrity"
 	Message string `json:"message"` // user-facing text
 }
@@ -78,6 +106,14 @@ type SimpleMessage struct {
 	Message string `json:"message"`
 }
 
+// JoinedMessage confirms a player's entry, as stored after any masking by
+// the content filter.
+type JoinedMessage struct {
+	Type      string `json:"type"` // "joined"
+	Username  string `json:"username"`
+	Celebrity string `json:"celebrity"`
+}
+
 // LobbyStateMessage informs clients about lock/unlock changes.
 type LobbyStateMessage struct {
 	Type   string `json:"type"` // "lobby_state"
@@ -85,27 +121,65 @@ type LobbyStateMessage struct {
 }
 
 // SessionInfoMessage is sent immediately on connect so the client knows
-// whether the lobby is locked and what role this cookie has.
+// whether the lobby is locked and what role this cookie has. It is sent again
+// to every client whenever the moderators change.
 type SessionInfoMessage struct {
-	Type        string `json:"type"`               // "session_info"
-	LobbyLocked bool   `json:"lobby_locked"`       // current lobby lock state
-	IsExisting  bool   `json:"is_existing"`        // true if this cookie already has a player
-	IsModerator bool   `json:"is_moderator"`       // true if this cookie is the moderator
-	Username    string `json:"username,omitempty"` // known username for this cookie, if any
+	Type         string `json:"type"`               // "session_info"
+	LobbyLocked  bool   `json:"lobby_locked"`       // current lobby lock state
+	IsExisting   bool   `json:"is_existing"`        // true if this cookie already has a player
+	IsModerator  bool   `json:"is_moderator"`       // true if this cookie is the host or a co-moderator
+	IsHost       bool   `json:"is_host"`            // true if this cookie is the host, who manages moderators
+	IsSpectator  bool   `json:"is_spectator"`       // true if this cookie is watching without playing
+	Invited      bool   `json:"invited"`            // true if this cookie may join a locked lobby without a PIN
+	CoModerators int    `json:"co_moderators"`      // number of co-moderators
+	PINRequired  bool   `json:"pin_required"`       // true if joining requires a PIN
+	Code         string `json:"code"`               // identifies this device to the moderator
+	Username     string `json:"username,omitempty"` // known username for this cookie, if any
 }
 
 // ModeratorViewMessage is sent only to the moderator with full mapping.
 type ModeratorViewMessage struct {
 	Type        string            `json:"type"` // "moderator_view"
 	Players     []ModeratorPlayer `json:"players"`
+	Pending     []ModeratorPlayer `json:"pending,omitempty"` // held by the content filter
+	Devices     []ModeratorDevice `json:"devices"`           // connected cookies which have not joined
+	Spectators  int               `json:"spectators"`        // connected spectators
+	Displays    int               `json:"displays"`          // paired displays
+	PINSet      bool              `json:"pin_set"`
+	FailedPINs  int               `json:"failed_pin_attempts"`
 	LobbyLocked bool              `json:"lobby_locked"`
 	CreatedAt   time.Time         `json:"created_at"`
 	LastActive  time.Time         `json:"last_active"`
+
+	// CelebritiesHidden is set for a moderator who is also playing, who is
+	// only told their own celebrity.
+	CelebritiesHidden bool `json:"celebrities_hidden,omitempty"`
 }
 
 type ModeratorPlayer struct {
 	Username  string `json:"username"`
 	Celebrity string `json:"celebrity"`
+	Team      string `json:"team,omitempty"`
+}
+
+// ModeratorDevice describes a connected cookie which has not joined as a
+// player, such as a moderator or a shared screen.
+type ModeratorDevice struct {
+	Code        string    `json:"code"`
+	Role        string    `json:"role"` // "host", "co_moderator", "spectator", or "viewer"
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// InviteMessage is sent only to the moderator with a newly created invite or
+// reclaim link.
+type InviteMessage struct {
+	Type      string    `json:"type"` // "invite"
+	Kind      string    `json:"kind"` // "invite" or "reclaim"
+	Path      string    `json:"path"` // relative to the server root, including the token
+	Role      string    `json:"role,omitempty"`
+	Team      string    `json:"team,omitempty"`
+	SingleUse bool      `json:"single_use"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
 }
 
 // TeamState is sent as part of game_state to show teams.
@@ -125,6 +199,28 @@ type GameStateMessage struct {
 	Teams       []TeamState `json:"teams,omitempty"`        // current teams
 }
 
+// DisplayPairingMessage shows a display the code a moderator must enter to
+// pair it with the game.
+type DisplayPairingMessage struct {
+	Type string `json:"type"` // "display_pairing"
+	Code string `json:"code"`
+}
+
+// DisplayPairedMessage tells a display it has been paired, with a token to
+// reconnect without pairing again.
+type DisplayPairedMessage struct {
+	Type  string `json:"type"` // "display_paired"
+	Token string `json:"token"`
+}
+
+// ModeratorVoteMessage asks players to choose a new moderator, when the host
+// has been disconnected for the grace period.
+type ModeratorVoteMessage struct {
+	Type       string    `json:"type"`       // "moderator_vote"
+	Candidates []string  `json:"candidates"` // usernames, longest connected first
+	EndsAt     time.Time `json:"ends_at"`
+}
+
 // GuessResultMessage informs everyone about a guess outcome.
 type GuessResultMessage struct {
 	Type      string `json:"type"`              // "guess_result"
@@ -135,66 +231,158 @@ type GuessResultMessage struct {
 	Message   string `json:"message,omitempty"` // human-readable summary
 }
 
+// outgoingMessage is implemented by every message sent to a client or
+// display, so that its type can be counted without inspecting it.
+type outgoingMessage interface {
+	MessageType() string
+}
+
+func (m CelebrityListMessage) MessageType() string  { return m.Type }
+func (m CollisionMessage) MessageType() string      { return m.Type }
+func (m SimpleMessage) MessageType() string         { return m.Type }
+func (m JoinedMessage) MessageType() string         { return m.Type }
+func (m LobbyStateMessage) MessageType() string     { return m.Type }
+func (m SessionInfoMessage) MessageType() string    { return m.Type }
+func (m ModeratorViewMessage) MessageType() string  { return m.Type }
+func (m InviteMessage) MessageType() string         { return m.Type }
+func (m GameStateMessage) MessageType() string      { return m.Type }
+func (m DisplayPairingMessage) MessageType() string { return m.Type }
+func (m DisplayPairedMessage) MessageType() string  { return m.Type }
+func (m ModeratorVoteMessage) MessageType() string  { return m.Type }
+func (m GuessResultMessage) MessageType() string    { return m.Type }
+
 type Client struct {
 	conn     *websocket.Conn
-	send     chan any
+	send     chan outgoingMessage
+	commands *tokenBucket // only used by readPump
+	admitted chan bool    // whether the hub accepted the client, sent once
 	playerID string
+	remoteIP string
+	addr     netip.Addr // client address, used for bans
+	invite   string     // invite token given when connecting, if any
+	log      *slog.Logger
+
+	connectedAt time.Time
+	received    atomic.Int64
+	sent        atomic.Int64
+
+	ctx  context.Context // carries the WebSocket session span
+	span *Span
+}
+
+// Display is a read-only view of a game on a shared screen, such as a TV.
+// Displays are kept apart from clients, so they never hold a role in the game
+// and are only sent public state, once a moderator has paired them.
+type Display struct {
+	conn   *websocket.Conn
+	send   chan outgoingMessage
+	token  string // display token given when connecting, if any
+	code   string // pairing code shown on the display
+	paired bool
+	log    *slog.Logger
+
+	connectedAt time.Time
 }
 
 type joinRequest struct {
 	client *Client
 	msg    ClientMessage
+	span   *Span
 }
 
 type modCommand struct {
 	client *Client
 	msg    ClientMessage
+	span   *Span
 }
 
 type guessRequest struct {
 	client *Client
 	msg    ClientMessage
+	span   *Span
+}
+
+type voteRequest struct {
+	client *Client
+	msg    ClientMessage
+	span   *Span
 }
 
 type Hub struct {
-	id      string
-	clients map[*Client]bool
-	players []Player
+	id        string
+	game      string
+	creatorIP string // counted against quota until the game is removed
+	log       *slog.Logger
+	clients   map[*Client]bool
+	players   []Player
+	pending   map[string]Player // PlayerID -> entry awaiting moderator approval
 
 	register chan *Client
 	unreg    chan *Client
 	joins    chan joinRequest
 	mods     chan modCommand
 	guesses  chan guessRequest
+	votes    chan voteRequest
 
 	mu sync.RWMutex
 
 	createdAt         time.Time
 	lastActive        time.Time
 	lobbyLocked       bool
-	moderatorPlayerID string // cookie/playerID of moderator (never in players)
+	pin               string          // required to join, if set
+	failedPINs        int             // incorrect PINs entered
+	pinLimiter        *rateLimiter    // limits PIN attempts by address
+	moderatorPlayerID string          // cookie/playerID of the host moderator (never in players)
+	coModerators      map[string]bool // PlayerIDs sharing the moderator's powers (never in players)
+	spectators        map[string]bool // PlayerIDs watching without playing (never in players)
+	displays          map[*Display]bool
+
+	grants      map[string]inviteGrant // PlayerID -> redeemed invite
+	usedInvites map[string]bool        // IDs of redeemed single-use invites
+	reclaimID   string                 // ID of the current moderator reclaim link, if any
+
+	// Failover of the host role while the host is disconnected.
+	failoverGen     int            // incremented whenever the host disconnects
+	failoverPending bool           // the grace period passed with nobody to take over
+	awayHost        string         // PlayerID of the host to restore when they reconnect
+	awayHostCoMod   bool           // whether the interim host was a co-moderator
+	vote            *moderatorVote // vote for a new host, if one is being held
 
 	gameStarted bool
+	startedAt   time.Time
 	turnOrder   []string          // slice of PlayerID in turn order
 	currentTurn int               // index into turnOrder
 	eliminated  map[string]bool   // PlayerID -> out?
 	teams       map[string]string // union-find parent: playerID -> parentID
+	teamsSeeded bool              // invites placed players on teams
 }
 
-func newHub(gameID string) *Hub {
+func newHub(game, gameID, creatorIP string) *Hub {
 	now := time.Now()
 	return &Hub{
-		id:         gameID,
-		clients:    make(map[*Client]bool),
-		register:   make(chan *Client),
-		unreg:      make(chan *Client),
-		joins:      make(chan joinRequest),
-		mods:       make(chan modCommand),
-		guesses:    make(chan guessRequest),
-		createdAt:  now,
-		lastActive: now,
-		eliminated: make(map[string]bool),
-		teams:      make(map[string]string),
+		id:        gameID,
+		game:      game,
+		creatorIP: creatorIP,
+		log:       slog.With("game_type", game, "game_id", gameID),
+		clients:   make(map[*Client]bool),
+		pending:   make(map[string]Player),
+		grants:    make(map[string]inviteGrant),
+
+		coModerators: make(map[string]bool),
+		spectators:   make(map[string]bool),
+		displays:     make(map[*Display]bool),
+		register:     make(chan *Client),
+		unreg:        make(chan *Client),
+		joins:        make(chan joinRequest),
+		mods:         make(chan modCommand),
+		guesses:      make(chan guessRequest),
+		votes:        make(chan voteRequest),
+		createdAt:    now,
+		lastActive:   now,
+		eliminated:   make(map[string]bool),
+		teams:        make(map[string]string),
+
+		usedInvites: make(map[string]bool),
 	}
 }
 
@@ -205,25 +393,44 @@ func (h *Hub) run(cfg *Config) {
 			h.mu.Lock()
 			h.lastActive = time.Now()
 
+			if err := h.admitLocked(cfg, c); err != nil {
+				h.mu.Unlock()
+
+				c.log.Warn("Refused WebSocket connection", "error", err)
+				c.send <- SimpleMessage{
+					Type:    "error",
+					Message: err.Error(),
+				}
+				close(c.send)
+				c.admitted <- false
+
+				continue
+			}
+
+			var inviteResult outgoingMessage
+			if c.invite != "" {
+				inviteResult = h.redeemInviteLocked(cfg, c)
+			}
+
 			if h.moderatorPlayerID == "" {
 				h.moderatorPlayerID = c.playerID
 			}
 
-			isExisting := false
-			existingName := ""
-			for _, p := range h.players {
-				if p.PlayerID == c.playerID {
-					isExisting = true
-					existingName = p.Username
-					break
-				}
-			}
-			isModerator := (h.moderatorPlayerID == c.playerID)
+			h.hostReturnedLocked(c)
+
+			seesMapping := h.seesMappingLocked(c.playerID)
 
 			h.clients[c] = true
+			c.admitted <- true
+
+			// The newcomer may be able to take over from an absent host,
+			// once they have been sent everything else.
+			if h.failoverPending && h.canSucceedLocked(c.playerID) {
+				go h.retryFailover(cfg)
+			}
 
 			var celebs []string
-			if h.gameStarted || isModerator {
+			if h.gameStarted || seesMapping {
 				celebs = h.currentCelebritiesLocked()
 			} else {
 				celebs = []string{}
@@ -233,13 +440,7 @@ func (h *Hub) run(cfg *Config) {
 
 			h.sendModeratorViewLocked()
 
-			c.send <- SessionInfoMessage{
-				Type:        "session_info",
-				LobbyLocked: h.lobbyLocked,
-				IsExisting:  isExisting,
-				IsModerator: isModerator,
-				Username:    existingName,
-			}
+			c.send <- h.sessionInfoLocked(c)
 
 			h.mu.Unlock()
 
@@ -249,6 +450,10 @@ func (h *Hub) run(cfg *Config) {
 			}
 			c.send <- gameState
 
+			if inviteResult != nil {
+				c.send <- inviteResult
+			}
+
 		case c := <-h.unreg:
 			h.mu.Lock()
 			h.lastActive = time.Now()
@@ -258,23 +463,156 @@ func (h *Hub) run(cfg *Config) {
 				close(c.send)
 			}
 			playerID := c.playerID
-			isModerator := (playerID == h.moderatorPlayerID)
+			isModerator := h.isModeratorLocked(playerID)
+
+			hostGone := playerID != "" && playerID == h.moderatorPlayerID && !h.connectedLocked(playerID)
+			if hostGone {
+				h.failoverGen++
+			}
+			gen := h.failoverGen
+
+			h.sendModeratorViewLocked()
 			h.mu.Unlock()
 
 			if playerID != "" && !isModerator {
-				go h.scheduleRemoval(playerID, cfg.playerTimeout)
+				go h.scheduleRemoval(playerID, cfg.gameSettings(h.game).PlayerTimeout)
+			}
+
+			if hostGone && cfg.moderatorFailover != failoverOff {
+				go h.scheduleFailover(cfg, playerID, gen)
 			}
 
 		case jr := <-h.joins:
-			h.handleJoin(cfg, jr)
+			if jr.msg.Type == "spectate" {
+				h.handleSpectate(jr)
+			} else {
+				h.handleJoin(cfg, jr)
+			}
 
 		case cmd := <-h.mods:
-			h.handleModCommand(cmd)
+			h.handleModCommand(cfg, cmd)
 
 		case gr := <-h.guesses:
 			h.handleGuess(cfg, gr)
+
+		case vr := <-h.votes:
+			h.handleVote(vr)
+		}
+	}
+}
+
+// admitLocked checks whether a new connection fits within the per-game
+// limits. Moderators are never refused for the game being full.
+func (h *Hub) admitLocked(cfg *Config, c *Client) error {
+	total, mine := 0, 0
+	for other := range h.clients {
+		total++
+		if other.playerID == c.playerID {
+			mine++
+		}
+	}
+
+	limits := cfg.limits()
+
+	if limits.MaxConnectionsPerPlayer > 0 && mine >= limits.MaxConnectionsPerPlayer {
+		return errTooManyConnections
+	}
+	if limits.MaxConnections > 0 && total >= limits.MaxConnections && !h.isModeratorLocked(c.playerID) {
+		return errGameFull
+	}
+
+	return nil
+}
+
+// sessionInfoLocked describes the lobby and the role of a client's cookie.
+func (h *Hub) sessionInfoLocked(c *Client) SessionInfoMessage {
+	msg := SessionInfoMessage{
+		Type:         "session_info",
+		LobbyLocked:  h.lobbyLocked,
+		IsModerator:  h.isModeratorLocked(c.playerID),
+		IsHost:       h.moderatorPlayerID == c.playerID,
+		IsSpectator:  h.isSpectatorLocked(c.playerID),
+		CoModerators: len(h.coModerators),
+		Invited:      h.grants[c.playerID].role == rolePlayer,
+		PINRequired:  h.pin != "" && h.grants[c.playerID].role != rolePlayer,
+		Code:         h.deviceCode(c.playerID),
+	}
+
+	for _, p := range h.players {
+		if p.PlayerID == c.playerID {
+			msg.IsExisting = true
+			msg.Username = p.Username
+			break
+		}
+	}
+
+	return msg
+}
+
+// isModeratorLocked reports whether a cookie is the host or a co-moderator.
+func (h *Hub) isModeratorLocked(playerID string) bool {
+	return playerID != "" && (playerID == h.moderatorPlayerID || h.coModerators[playerID])
+}
+
+// isSpectatorLocked reports whether a cookie is watching without playing.
+// Spectators who are made moderators stop being spectators.
+// seesMappingLocked reports whether a cookie may see who picked each
+// celebrity: a moderator who is not playing. A player who has taken over
+// from an absent host moderates without it.
+func (h *Hub) seesMappingLocked(playerID string) bool {
+	return h.isModeratorLocked(playerID) && !h.isPlayerLocked(playerID)
+}
+
+func (h *Hub) isSpectatorLocked(playerID string) bool {
+	return h.spectators[playerID] && !h.isModeratorLocked(playerID)
+}
+
+// deviceCode returns a short code identifying a cookie within this game,
+// which the moderator uses to pick devices without learning player IDs.
+func (h *Hub) deviceCode(playerID string) string {
+	sum := sha256.Sum256([]byte(h.id + "/" + playerID))
+
+	return strings.ToUpper(hex.EncodeToString(sum[:3]))
+}
+
+// connectedLocked reports whether a cookie has any connected clients.
+func (h *Hub) connectedLocked(playerID string) bool {
+	for c := range h.clients {
+		if c.playerID == playerID {
+			return true
 		}
 	}
+
+	return false
+}
+
+// notify sends a message to a client from outside the hub, if it is still
+// connected.
+func (h *Hub) notify(c *Client, msg outgoingMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+
+	select {
+	case c.send <- msg:
+	default:
+		h.dropClientLocked(c)
+	}
+}
+
+// dropClientLocked disconnects a client whose send buffer is full.
+func (h *Hub) dropClientLocked(c *Client) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+
+	delete(h.clients, c)
+	close(c.send)
+
+	metrics.droppedClients.inc(h.game)
 }
 
 func (h *Hub) currentCelebritiesLocked() []string {
@@ -301,7 +639,7 @@ func (h *Hub) broadcastCelebritiesLocked() {
 
 	for client := range h.clients {
 		var celebs []string
-		if h.gameStarted || client.playerID == h.moderatorPlayerID {
+		if h.gameStarted || h.seesMappingLocked(client.playerID) {
 			celebs = celebsAll
 		} else {
 			celebs = []string{}
@@ -313,10 +651,11 @@ func (h *Hub) broadcastCelebritiesLocked() {
 			Celebrities: celebs,
 		}:
 		default:
-			delete(h.clients, client)
-			close(client.send)
+			h.dropClientLocked(client)
 		}
 	}
+
+	h.sendToDisplaysLocked(h.displayCelebritiesLocked())
 }
 
 func (h *Hub) teamFindLocked(id string) string {
@@ -342,6 +681,66 @@ func (h *Hub) teamUnionLocked(a, b string) {
 	h.teams[rb] = ra
 }
 
+// seedTeamsLocked places players assigned to the same team by their invites
+// on one team before the game begins.
+func (h *Hub) seedTeamsLocked() {
+	first := make(map[string]string)
+	h.teamsSeeded = false
+
+	for _, p := range h.players {
+		if p.Team == "" {
+			continue
+		}
+
+		if id, ok := first[p.Team]; ok {
+			h.teamUnionLocked(id, p.PlayerID)
+			h.teamsSeeded = true
+		} else {
+			first[p.Team] = p.PlayerID
+		}
+	}
+}
+
+// activeTeamsLocked returns the root of each team with a player still in.
+func (h *Hub) activeTeamsLocked() []string {
+	var roots []string
+
+	for _, p := range h.players {
+		if h.eliminated[p.PlayerID] {
+			continue
+		}
+
+		if root := h.teamFindLocked(p.PlayerID); !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+
+	return roots
+}
+
+// activePlayersLocked returns the IDs of players who have not been eliminated.
+func (h *Hub) activePlayersLocked() []string {
+	var ids []string
+
+	for _, p := range h.players {
+		if !h.eliminated[p.PlayerID] {
+			ids = append(ids, p.PlayerID)
+		}
+	}
+
+	return ids
+}
+
+// remainingLocked returns the number of sides still in the game: teams when
+// invites seeded them, otherwise individual players.
+func (h *Hub) remainingLocked() int {
+	if h.teamsSeeded {
+		return len(h.activeTeamsLocked())
+	}
+
+	return len(h.activePlayersLocked())
+}
+
 // startGameLocked freezes and shuffles the turn order and marks the game started.
 func (h *Hub) startGameLocked() {
 	if h.gameStarted {
@@ -376,12 +775,14 @@ func (h *Hub) startGameLocked() {
 	h.turnOrder = ids
 	h.currentTurn = 0
 	h.gameStarted = true
+	h.startedAt = time.Now()
 	if h.eliminated == nil {
 		h.eliminated = make(map[string]bool)
 	}
 	if h.teams == nil {
 		h.teams = make(map[string]string)
 	}
+	h.seedTeamsLocked()
 
 	h.broadcastCelebritiesLocked()
 	h.sendModeratorViewLocked()
@@ -428,6 +829,8 @@ func (h *Hub) restartGameLocked() {
 	h.turnOrder = ids
 	h.currentTurn = 0
 	h.gameStarted = true
+	h.startedAt = time.Now()
+	h.seedTeamsLocked()
 
 	h.broadcastCelebritiesLocked()
 	h.sendModeratorViewLocked()
@@ -454,12 +857,18 @@ func (h *Hub) scheduleRemoval(playerID string, d time.Duration) {
 			changed = true
 			delete(h.eliminated, p.PlayerID)
 			delete(h.teams, p.PlayerID)
+			delete(h.grants, p.PlayerID)
 			continue
 		}
 		dst = append(dst, p)
 	}
 	h.players = dst
 
+	if _, ok := h.pending[playerID]; ok {
+		delete(h.pending, playerID)
+		h.sendModeratorViewLocked()
+	}
+
 	if !changed {
 		return
 	}
@@ -475,6 +884,11 @@ func (h *Hub) handleJoin(cfg *Config, jr joinRequest) {
 	msg := jr.msg
 	c := jr.client
 
+	defer jr.span.End()
+
+	msg.Username = strings.TrimSpace(msg.Username)
+	msg.Celebrity = strings.TrimSpace(msg.Celebrity)
+
 	if msg.Username == "" || msg.Celebrity == "" || c.playerID == "" {
 		return
 	}
@@ -484,164 +898,393 @@ func (h *Hub) handleJoin(cfg *Config, jr joinRequest) {
 
 	h.lastActive = time.Now()
 
-	existingIndex := -1
-	for i, p := range h.players {
-		if p.PlayerID == c.playerID {
-			existingIndex = i
-			break
-		}
-	}
-
-	if h.lobbyLocked && existingIndex == -1 {
+	if h.isSpectatorLocked(c.playerID) {
 		select {
 		case c.send <- SimpleMessage{
-			Type:    "lobby_locked",
-			Message: "The lobby is locked; no new players may join.",
+			Type:    "spectating",
+			Message: "Spectators cannot join the game. Ask the moderator to let you play.",
 		}:
 		default:
-			delete(h.clients, c)
-			close(c.send)
+			h.dropClientLocked(c)
 		}
 		return
 	}
 
-	collisionField := ""
-	for _, p := range h.players {
-		if p.PlayerID == c.playerID {
-			continue
+	limits := cfg.limits()
+
+	for _, f := range []struct {
+		name, value string
+		max         int
+	}{
+		{"username", msg.Username, limits.MaxUsernameLength},
+		{"celebrity", msg.Celebrity, limits.MaxCelebrityLength},
+	} {
+		problem := ""
+		switch {
+		case f.max > 0 && utf8.RuneCountInString(f.value) > f.max:
+			problem = fmt.Sprintf("That %s is too long. Please use at most %d characters.", f.name, f.max)
+		case newNameKey(f.value).empty():
+			problem = fmt.Sprintf("That %s is blank. Please use visible characters.", f.name)
 		}
-		if p.Username == msg.Username {
-			collisionField = "username"
-			break
-		}
-		if p.Celebrity == msg.Celebrity {
-			collisionField = "celebrity"
-			break
+
+		if problem != "" {
+			select {
+			case c.send <- CollisionMessage{
+				Type:    "invalid_field",
+				Field:   f.name,
+				Message: problem,
+			}:
+			default:
+				h.dropClientLocked(c)
+			}
+			return
 		}
 	}
 
-	if collisionField != "" {
-		msgText := "That value is already taken."
-		switch collisionField {
-		case "username":
-			msgText = "That username is already taken. Please choose a different username."
-		case "celebrity":
-			msgText = "That celebrity name has already been used. Please choose a different celebrity."
+	var held bool
+
+	filter := cfg.contentFilterInUse()
+
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"username", &msg.Username},
+		{"celebrity", &msg.Celebrity},
+	} {
+		masked, matched := filter.check(*f.value)
+		if !matched {
+			continue
+		}
+
+		c.log.Info("Filtered text", "field", f.name, "action", filter.action)
+		metrics.filtered.inc(h.game, f.name, filter.action)
+
+		switch filter.action {
+		case filterReject:
+			select {
+			case c.send <- CollisionMessage{
+				Type:    "invalid_field",
+				Field:   f.name,
+				Message: fmt.Sprintf("That %s is not allowed. Please choose a different %s.", f.name, f.name),
+			}:
+			default:
+				h.dropClientLocked(c)
+			}
+			return
+		case filterMask:
+			*f.value = masked
+		case filterApprove:
+			held = true
 		}
+	}
 
+	if refusal := h.admitPlayerLocked(cfg, c.playerID, msg.Username, msg.Celebrity); refusal != nil {
 		select {
-		case c.send <- CollisionMessage{
-			Type:    "collision",
-			Field:   collisionField,
-			Message: msgText,
-		}:
+		case c.send <- refusal:
 		default:
-			delete(h.clients, c)
-			close(c.send)
+			h.dropClientLocked(c)
 		}
 		return
 	}
 
-	if existingIndex >= 0 {
-		h.players[existingIndex].Username = msg.Username
-		h.players[existingIndex].Celebrity = msg.Celebrity
-	} else {
-		h.players = append(h.players, Player{
+	// The PIN is checked last, so that an attempt is only used up by a
+	// join which would otherwise succeed.
+	invited := h.grants[c.playerID].role == rolePlayer
+
+	if h.pin != "" && !invited && !slices.ContainsFunc(h.players, func(p Player) bool { return p.PlayerID == c.playerID }) {
+		if refusal := h.checkPINLocked(c, msg.PIN); refusal != nil {
+			select {
+			case c.send <- refusal:
+			default:
+				h.dropClientLocked(c)
+			}
+			return
+		}
+	}
+
+	if held {
+		h.pending[c.playerID] = Player{
 			PlayerID:  c.playerID,
 			Username:  msg.Username,
 			Celebrity: msg.Celebrity,
-		})
-		logf(cfg, "GAMES: Player %q joined %s", msg.Username, h.id)
+		}
+		c.log.Info("Player awaiting approval", "username", msg.Username)
+
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "pending_approval",
+			Message: "Waiting for the moderator to approve your entry.",
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		h.sendModeratorViewLocked()
+		return
 	}
 
-	h.broadcastCelebritiesLocked()
-	h.sendModeratorViewLocked()
-	h.broadcastGameStateLocked()
+	h.addPlayerLocked(c.log, c.playerID, msg.Username, msg.Celebrity)
 }
 
-func (h *Hub) handleGuess(cfg *Config, gr guessRequest) {
-	c := gr.client
-	msg := gr.msg
+// handleSpectate makes a client's cookie a spectator, if it has not joined.
+func (h *Hub) handleSpectate(jr joinRequest) {
+	c := jr.client
 
-	if c.playerID == "" || msg.Celebrity == "" || msg.TargetUsername == "" {
-		return
-	}
+	defer jr.span.End()
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	h.lastActive = time.Now()
 
-	if !h.gameStarted || len(h.turnOrder) == 0 {
+	if c.playerID == "" || h.isModeratorLocked(c.playerID) || h.isPlayerLocked(c.playerID) || h.spectators[c.playerID] {
 		return
 	}
 
-	var guesser *Player
-	for i := range h.players {
-		if h.players[i].PlayerID == c.playerID {
-			guesser = &h.players[i]
-			break
-		}
-	}
-	if guesser == nil {
-		return
-	}
+	h.spectators[c.playerID] = true
+	c.log.Info("Spectating")
 
-	if h.eliminated[guesser.PlayerID] {
-		return
-	}
+	h.sendToPlayerLocked(c.playerID, h.sessionInfoLocked(c))
+	h.sendModeratorViewLocked()
+}
 
-	if h.turnOrder[h.currentTurn] != guesser.PlayerID {
-		select {
-		case c.send <- SimpleMessage{
-			Type:    "not_your_turn",
-			Message: "It is not your turn to guess.",
-		}:
-		default:
+// checkPINLocked verifies the PIN given by a new player, returning the
+// message to send them if it is missing or incorrect. Attempts are rate
+// limited by address, so that the PIN cannot be guessed by brute force.
+func (h *Hub) checkPINLocked(c *Client, pin string) outgoingMessage {
+	if pin == "" {
+		return SimpleMessage{
+			Type:    "pin_required",
+			Message: "This game requires a PIN to join.",
 		}
-		return
 	}
 
-	var owner *Player
-	for i := range h.players {
-		if h.players[i].Celebrity == msg.Celebrity {
-			owner = &h.players[i]
-			break
+	if ok, wait := h.pinLimiter.allow(h.id + "/" + c.addr.String()); !ok {
+		return SimpleMessage{
+			Type:    "rate_limited",
+			Message: fmt.Sprintf("Too many PIN attempts. Please wait %s seconds and try again.", retryAfter(wait)),
 		}
 	}
-	if owner == nil {
-		select {
-		case c.send <- SimpleMessage{
-			Type:    "guess_error",
-			Message: "That celebrity is not in the list.",
-		}:
-		default:
+
+	if subtle.ConstantTimeCompare([]byte(pin), []byte(h.pin)) != 1 {
+		h.failedPINs++
+		c.log.Info("Incorrect PIN", "failed_pin_attempts", h.failedPINs)
+		h.sendModeratorViewLocked()
+
+		return SimpleMessage{
+			Type:    "pin_required",
+			Message: "Incorrect PIN. Please try again.",
 		}
-		return
+	}
+
+	return nil
+}
+
+// admitPlayerLocked checks whether a player may join or update their entry,
+// returning the message to send them if not. Invited players may join a
+// locked lobby.
+func (h *Hub) admitPlayerLocked(cfg *Config, playerID, username, celebrity string) outgoingMessage {
+	existing := slices.ContainsFunc(h.players, func(p Player) bool {
+		return p.PlayerID == playerID
+	})
+	invited := h.grants[playerID].role == rolePlayer
+
+	if h.lobbyLocked && !existing && !invited {
+		return SimpleMessage{
+			Type:    "lobby_locked",
+			Message: "The lobby is locked; no new players may join.",
+		}
+	}
+
+	usernameKey, celebrityKey := newNameKey(username), newNameKey(celebrity)
+
+	collisionField := ""
+	for _, p := range slices.Concat(h.players, slices.Collect(maps.Values(h.pending))) {
+		if p.PlayerID == playerID {
+			continue
+		}
+		if newNameKey(p.Username).collides(usernameKey) {
+			collisionField = "username"
+			break
+		}
+		if newNameKey(p.Celebrity).collides(celebrityKey) {
+			collisionField = "celebrity"
+			break
+		}
+	}
+
+	if collisionField != "" {
+		msgText := "That value is already taken."
+		switch collisionField {
+		case "username":
+			msgText = "That username is already taken. Please choose a different username."
+		case "celebrity":
+			msgText = "That celebrity name has already been used. Please choose a different celebrity."
+		}
+
+		return CollisionMessage{
+			Type:    "collision",
+			Field:   collisionField,
+			Message: msgText,
+		}
+	}
+
+	if maxPlayers := cfg.limits().MaxPlayers; !existing && maxPlayers > 0 && len(h.players) >= maxPlayers {
+		return SimpleMessage{
+			Type:    "game_full",
+			Message: fmt.Sprintf("This game is full; at most %d players may join.", maxPlayers),
+		}
+	}
+
+	return nil
+}
+
+// addPlayerLocked adds a player, or updates their entry if they have
+// already joined.
+func (h *Hub) addPlayerLocked(log *slog.Logger, playerID, username, celebrity string) {
+	delete(h.pending, playerID)
+
+	i := slices.IndexFunc(h.players, func(p Player) bool {
+		return p.PlayerID == playerID
+	})
+
+	if i >= 0 {
+		h.players[i].Username = username
+		h.players[i].Celebrity = celebrity
+	} else {
+		h.players = append(h.players, Player{
+			PlayerID:  playerID,
+			Username:  username,
+			Celebrity: celebrity,
+			Team:      h.grants[playerID].team,
+		})
+		log.Info("Player joined", "username", username, "team", h.grants[playerID].team)
+	}
+
+	h.sendToPlayerLocked(playerID, JoinedMessage{
+		Type:      "joined",
+		Username:  username,
+		Celebrity: celebrity,
+	})
+
+	h.broadcastCelebritiesLocked()
+	h.sendModeratorViewLocked()
+	h.broadcastGameStateLocked()
+}
+
+// sendToPlayerLocked sends msg to every client of a player.
+func (h *Hub) sendToPlayerLocked(playerID string, msg outgoingMessage) {
+	for client := range h.clients {
+		if client.playerID != playerID {
+			continue
+		}
+
+		select {
+		case client.send <- msg:
+		default:
+			h.dropClientLocked(client)
+		}
+	}
+}
+
+func (h *Hub) handleGuess(cfg *Config, gr guessRequest) {
+	c := gr.client
+	msg := gr.msg
+
+	defer gr.span.End()
+
+	if c.playerID == "" || msg.Celebrity == "" || msg.TargetUsername == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastActive = time.Now()
+
+	if !h.gameStarted || len(h.turnOrder) == 0 {
+		return
+	}
+
+	var guesser *Player
+	for i := range h.players {
+		if h.players[i].PlayerID == c.playerID {
+			guesser = &h.players[i]
+			break
+		}
+	}
+	if guesser == nil {
+		return
+	}
+
+	if h.eliminated[guesser.PlayerID] {
+		return
+	}
+
+	if h.turnOrder[h.currentTurn] != guesser.PlayerID {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "not_your_turn",
+			Message: "It is not your turn to guess.",
+		}:
+		default:
+		}
+		return
+	}
+
+	var owner *Player
+	for i := range h.players {
+		if h.players[i].Celebrity == msg.Celebrity {
+			owner = &h.players[i]
+			break
+		}
+	}
+	if owner == nil {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "guess_error",
+			Message: "That celebrity is not in the list.",
+		}:
+		default:
+		}
+		return
+	}
+
+	if h.teamsSeeded && !h.eliminated[owner.PlayerID] && owner.PlayerID != guesser.PlayerID && h.teamFindLocked(owner.PlayerID) == h.teamFindLocked(guesser.PlayerID) {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "guess_error",
+			Message: "That celebrity belongs to your own team.",
+		}:
+		default:
+		}
+		return
 	}
 
 	correct := (owner.Username == msg.TargetUsername)
 
+	gr.span.SetAttributes("guess.correct", correct)
+
+	if correct {
+		metrics.guesses.inc(h.game, "correct")
+	} else {
+		metrics.guesses.inc(h.game, "incorrect")
+	}
+
 	var text string
 	if correct {
 		h.eliminated[owner.PlayerID] = true
 		h.teamUnionLocked(guesser.PlayerID, owner.PlayerID)
 		text = guesser.Username + " correctly guessed that \"" + owner.Celebrity + "\" belongs to " + owner.Username + "."
-		logf(cfg, "GAMES: %q correctly guessed %q for %q in %q", guesser.Username, owner.Username, owner.Celebrity, h.id)
+		c.log.Info("Correct guess", "guesser", guesser.Username, "target", owner.Username, "celebrity", owner.Celebrity)
 
-		activeCount := 0
-		for _, p := range h.players {
-			if h.eliminated[p.PlayerID] {
-				continue
-			}
-			activeCount++
-		}
-		if activeCount <= 1 {
+		if h.remainingLocked() <= 1 {
 			h.gameStarted = false
+			metrics.gameDuration.observe(time.Since(h.startedAt).Seconds(), h.game)
 		}
 	} else {
 		text = guesser.Username + " incorrectly guessed that \"" + msg.Celebrity + "\" belongs to " + msg.TargetUsername + "."
-		logf(cfg, "GAMES: %q incorrectly guessed %q for %q in %q", guesser.Username, msg.TargetUsername, msg.Celebrity, h.id)
+		c.log.Info("Incorrect guess", "guesser", guesser.Username, "target", msg.TargetUsername, "celebrity", msg.Celebrity)
 
 		if len(h.turnOrder) > 1 {
 			for i := 1; i <= len(h.turnOrder); i++ {
@@ -667,27 +1310,30 @@ func (h *Hub) handleGuess(cfg *Config, gr guessRequest) {
 		select {
 		case client.send <- result:
 		default:
-			delete(h.clients, client)
-			close(client.send)
+			h.dropClientLocked(client)
 		}
 	}
+	h.sendToDisplaysLocked(result)
 
 	h.broadcastCelebritiesLocked()
 	h.broadcastGameStateLocked()
 }
 
-// handleModCommand processes moderator commands: lock/unlock lobby, kick users,
-// start the game, restart the game.
-func (h *Hub) handleModCommand(cmd modCommand) {
+// handleModCommand processes moderator commands: lock/unlock lobby, kick (and
+// optionally ban) users, create invite links, manage moderators, pair
+// displays, start the game, restart the game.
+func (h *Hub) handleModCommand(cfg *Config, cmd modCommand) {
 	c := cmd.client
 	msg := cmd.msg
 
+	defer cmd.span.End()
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	h.lastActive = time.Now()
 
-	if h.moderatorPlayerID == "" || c.playerID != h.moderatorPlayerID {
+	if !h.isModeratorLocked(c.playerID) {
 		return
 	}
 
@@ -695,6 +1341,7 @@ func (h *Hub) handleModCommand(cmd modCommand) {
 	case "lock_lobby":
 		locked := msg.Lock != nil && *msg.Lock
 		h.lobbyLocked = locked
+		c.log.Info("Lobby lock changed", "locked", locked)
 
 		for client := range h.clients {
 			select {
@@ -703,8 +1350,7 @@ func (h *Hub) handleModCommand(cmd modCommand) {
 				Locked: locked,
 			}:
 			default:
-				delete(h.clients, client)
-				close(client.send)
+				h.dropClientLocked(client)
 			}
 		}
 		h.sendModeratorViewLocked()
@@ -725,6 +1371,7 @@ func (h *Hub) handleModCommand(cmd modCommand) {
 				kickedPlayerID = p.PlayerID
 				delete(h.eliminated, p.PlayerID)
 				delete(h.teams, p.PlayerID)
+				delete(h.grants, p.PlayerID)
 				continue
 			}
 			dst = append(dst, p)
@@ -735,8 +1382,16 @@ func (h *Hub) handleModCommand(cmd modCommand) {
 			return
 		}
 
+		c.log.Info("Player kicked", "username", target, "target_player_id", kickedPlayerID, "ban", msg.Ban, "ban_ip", msg.BanIP)
+
+		var addrs []netip.Addr
+
 		for client := range h.clients {
 			if client.playerID == kickedPlayerID {
+				if client.addr.IsValid() && !slices.Contains(addrs, client.addr) {
+					addrs = append(addrs, client.addr)
+				}
+
 				client.send <- SimpleMessage{
 					Type:    "kicked",
 					Message: "You have been removed by the moderator.",
@@ -746,56 +1401,1167 @@ func (h *Hub) handleModCommand(cmd modCommand) {
 			}
 		}
 
+		if msg.Ban {
+			h.banLocked(cfg, c, kickedPlayerID, target, addrs, msg.BanIP)
+		}
+
 		h.broadcastCelebritiesLocked()
 		h.sendModeratorViewLocked()
 		h.broadcastGameStateLocked()
 
-	case "start_game":
-		h.startGameLocked()
+	case "set_pin":
+		pin := strings.TrimSpace(msg.PIN)
+		if pin != "" && (utf8.RuneCountInString(pin) < minPINLength || utf8.RuneCountInString(pin) > maxPINLength) {
+			select {
+			case c.send <- SimpleMessage{
+				Type:    "invalid_pin",
+				Message: fmt.Sprintf("The PIN must be between %d and %d characters.", minPINLength, maxPINLength),
+			}:
+			default:
+				h.dropClientLocked(c)
+			}
+			return
+		}
+
+		h.pin = pin
+		h.failedPINs = 0
+		c.log.Info("Lobby PIN changed", "pin_set", pin != "")
+
+		h.sendModeratorViewLocked()
+
+	case "approve_join", "reject_join":
+		var entry Player
+		found := false
+		for _, p := range h.pending {
+			if p.Username == msg.TargetUsername {
+				entry, found = p, true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+
+		delete(h.pending, entry.PlayerID)
+
+		if msg.Type == "reject_join" {
+			c.log.Info("Player entry rejected", "username", entry.Username, "target_player_id", entry.PlayerID)
+
+			h.sendToPlayerLocked(entry.PlayerID, SimpleMessage{
+				Type:    "join_rejected",
+				Message: "The moderator did not approve your entry. Please choose a different username and celebrity.",
+			})
+			h.sendModeratorViewLocked()
+			return
+		}
+
+		c.log.Info("Player entry approved", "username", entry.Username, "target_player_id", entry.PlayerID)
+
+		if refusal := h.admitPlayerLocked(cfg, entry.PlayerID, entry.Username, entry.Celebrity); refusal != nil {
+			h.sendToPlayerLocked(entry.PlayerID, refusal)
+			h.sendModeratorViewLocked()
+			return
+		}
+
+		h.addPlayerLocked(h.log.With("player_id", entry.PlayerID), entry.PlayerID, entry.Username, entry.Celebrity)
+
+	case "create_invite":
+		h.createInviteLocked(cfg, c, msg)
+
+	case "create_reclaim_link":
+		h.createReclaimLinkLocked(cfg, c)
+
+	case "transfer_moderator", "add_co_moderator", "remove_co_moderator":
+		h.changeModeratorsLocked(c, msg)
+
+	case "promote_spectator":
+		h.promoteSpectatorLocked(c, msg)
+
+	case "pair_display":
+		h.pairDisplayLocked(cfg, c, msg)
+
+	case "start_game":
+		c.log.Info("Game started", "players", len(h.players))
+		h.startGameLocked()
+
+	case "restart_game":
+		c.log.Info("Game restarted", "players", len(h.players))
+		h.restartGameLocked()
+	}
+}
+
+// banLocked bans a kicked player from rejoining this game, by cookie and
+// optionally by every address they were connected from.
+func (h *Hub) banLocked(cfg *Config, c *Client, playerID, username string, addrs []netip.Addr, byIP bool) {
+	var expires time.Time
+	if cfg.gameBanDuration > 0 {
+		expires = time.Now().Add(cfg.gameBanDuration)
+	}
+
+	base := Ban{
+		Game:      h.game + "/" + h.id,
+		Reason:    "banned by moderator: " + username,
+		Source:    "moderator",
+		ExpiresAt: expires,
+	}
+
+	list := []Ban{base}
+	list[0].PlayerID = playerID
+
+	if byIP {
+		for _, addr := range addrs {
+			b := base
+			b.IP = addr.String()
+			list = append(list, b)
+		}
+	}
+
+	for _, b := range list {
+		ban, err := newBan(b)
+		if err != nil {
+			c.log.Error("Failed to ban player", "target_player_id", playerID, "error", err)
+
+			continue
+		}
+
+		if err := bans.add(ban); err != nil {
+			c.log.Error("Failed to save bans", "error", err)
+		}
+	}
+
+	c.log.Info("Player banned", "username", username, "target_player_id", playerID, "addresses", len(addrs), "ban_ip", byIP, "expires_at", expires)
+}
+
+// createInviteLocked signs an invite link for this game and sends it to the
+// moderator.
+func (h *Hub) createInviteLocked(cfg *Config, c *Client, msg ClientMessage) {
+	inv := &invite{
+		Kind:      inviteJoin,
+		Game:      h.game + "/" + h.id,
+		Created:   h.createdAt.UnixNano(),
+		Role:      cmp.Or(msg.Role, rolePlayer),
+		Team:      strings.TrimSpace(msg.Team),
+		SingleUse: msg.SingleUse,
+	}
+
+	maxLength := cfg.limits().MaxUsernameLength
+
+	problem := ""
+	switch {
+	case inv.Role != rolePlayer && inv.Role != roleSpectator:
+		problem = fmt.Sprintf("Unknown role %q.", inv.Role)
+	case maxLength > 0 && utf8.RuneCountInString(inv.Team) > maxLength:
+		problem = fmt.Sprintf("That team name is too long. Please use at most %d characters.", maxLength)
+	case msg.Duration != "":
+		d, err := time.ParseDuration(msg.Duration)
+		if err != nil || d <= 0 {
+			problem = fmt.Sprintf("Invalid expiry %q (must be positive, e.g. 1h).", msg.Duration)
+			break
+		}
+		inv.Expires = time.Now().Add(d).Unix()
+	}
+
+	if problem != "" {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "invite_error",
+			Message: problem,
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		return
+	}
+
+	h.sendInviteLocked(cfg, c, inv)
+}
+
+// createReclaimLinkLocked creates a link which makes whoever opens it the
+// host, invalidating any earlier one. Only the host may create one.
+func (h *Hub) createReclaimLinkLocked(cfg *Config, c *Client) {
+	if c.playerID != h.moderatorPlayerID {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "moderator_error",
+			Message: "Only the host can create a reclaim link.",
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		return
+	}
+
+	inv := &invite{
+		Kind:      inviteReclaim,
+		Game:      h.game + "/" + h.id,
+		Created:   h.createdAt.UnixNano(),
+		SingleUse: true,
+	}
+
+	if h.sendInviteLocked(cfg, c, inv) {
+		h.reclaimID = inv.ID
+	}
+}
+
+// sendInviteLocked assigns an ID to an invite, signs it, and sends the link
+// to the moderator, reporting whether it succeeded.
+func (h *Hub) sendInviteLocked(cfg *Config, c *Client, inv *invite) bool {
+	id, err := newInviteID()
+	if err != nil {
+		c.log.Error("Failed to create invite", "error", err)
+
+		return false
+	}
+	inv.ID = id
+
+	token, err := encodeInvite(cfg.cookieKeys()[0], inv)
+	if err != nil {
+		c.log.Error("Failed to create invite", "error", err)
+
+		return false
+	}
+
+	c.log.Info("Invite created", "kind", inv.Kind, "role", inv.Role, "team", inv.Team, "single_use", inv.SingleUse, "expires_at", inv.expiresAt())
+
+	select {
+	case c.send <- InviteMessage{
+		Type:      "invite",
+		Kind:      inv.Kind,
+		Path:      cfg.prefix + "/" + h.game + "/" + h.id + "?" + inviteParam + "=" + url.QueryEscape(token),
+		Role:      inv.Role,
+		Team:      inv.Team,
+		SingleUse: inv.SingleUse,
+		ExpiresAt: inv.expiresAt(),
+	}:
+	default:
+		h.dropClientLocked(c)
+	}
+
+	return true
+}
+
+// redeemInviteLocked applies the invite a client connected with, returning
+// the message to send them if it was refused.
+func (h *Hub) redeemInviteLocked(cfg *Config, c *Client) outgoingMessage {
+	inv, err := decodeInvite(cfg.cookieKeys(), c.invite, time.Now())
+	if err == nil && (inv.Game != h.game+"/"+h.id || inv.Created != h.createdAt.UnixNano()) {
+		err = errInvalidInvite
+	}
+
+	if err == nil {
+		switch inv.Kind {
+		case inviteJoin:
+			err = h.grantLocked(c, inv)
+		case inviteReclaim:
+			err = h.reclaimLocked(c, inv)
+		default:
+			err = errInvalidInvite
+		}
+	}
+
+	if err != nil {
+		c.log.Info("Refused invite", "error", err)
+
+		return SimpleMessage{
+			Type:    "invite_error",
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) isPlayerLocked(playerID string) bool {
+	_, pending := h.pending[playerID]
+
+	return pending || slices.ContainsFunc(h.players, func(p Player) bool {
+		return p.PlayerID == playerID
+	})
+}
+
+// grantLocked records the role and team an invite assigns to a client's
+// cookie, for when they join.
+func (h *Hub) grantLocked(c *Client, inv *invite) error {
+	switch {
+	case h.isModeratorLocked(c.playerID):
+		return nil
+	case h.isPlayerLocked(c.playerID):
+		return errAlreadyJoined
+	case inv.SingleUse && h.usedInvites[inv.ID]:
+		return errUsedInvite
+	}
+
+	if inv.SingleUse {
+		h.usedInvites[inv.ID] = true
+	}
+
+	if inv.Role == roleSpectator {
+		h.spectators[c.playerID] = true
+	} else {
+		delete(h.spectators, c.playerID)
+	}
+
+	h.grants[c.playerID] = inviteGrant{
+		role: inv.Role,
+		team: inv.Team,
+	}
+
+	c.log.Info("Invite redeemed", "role", inv.Role, "team", inv.Team, "single_use", inv.SingleUse)
+
+	return nil
+}
+
+// reclaimLocked makes a client's cookie the moderator, in place of the
+// previous moderator cookie.
+func (h *Hub) reclaimLocked(c *Client, inv *invite) error {
+	switch {
+	case c.playerID == h.moderatorPlayerID:
+		return nil
+	case inv.ID != h.reclaimID:
+		return errUsedInvite
+	case h.isPlayerLocked(c.playerID):
+		return errAlreadyJoined
+	}
+
+	previous := h.moderatorPlayerID
+	h.moderatorPlayerID = c.playerID
+	delete(h.coModerators, c.playerID)
+	h.reclaimID = ""
+	h.awayHost = ""
+	h.vote = nil
+
+	c.log.Info("Moderator reclaimed", "previous_player_id", previous)
+
+	h.moderatorsChangedLocked("The host has moved to another device.")
+
+	return nil
+}
+
+// changeModeratorsLocked transfers the host role to, or adds or removes, a
+// connected device which has not joined as a player. Only the host may
+// change moderators.
+func (h *Hub) changeModeratorsLocked(c *Client, msg ClientMessage) {
+	var target string
+	for client := range h.clients {
+		if msg.Target != "" && h.deviceCode(client.playerID) == strings.ToUpper(msg.Target) {
+			target = client.playerID
+			break
+		}
+	}
+
+	problem := ""
+	switch {
+	case c.playerID != h.moderatorPlayerID:
+		problem = "Only the host can change moderators."
+	case target == "":
+		problem = "That device is no longer connected."
+	case h.isPlayerLocked(target):
+		problem = "Players cannot be made moderators."
+	case target == h.moderatorPlayerID:
+		problem = "That device is already the host."
+	case msg.Type == "add_co_moderator" && h.coModerators[target]:
+		problem = "That device is already a co-moderator."
+	case msg.Type == "remove_co_moderator" && !h.coModerators[target]:
+		problem = "That device is not a co-moderator."
+	}
+
+	if problem != "" {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "moderator_error",
+			Message: problem,
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		return
+	}
+
+	var notice string
+
+	switch msg.Type {
+	case "transfer_moderator":
+		delete(h.coModerators, target)
+		h.moderatorPlayerID = target
+		h.reclaimID = ""
+		notice = "The host has handed moderator rights to another device."
+	case "add_co_moderator":
+		h.coModerators[target] = true
+		notice = "A co-moderator has been added."
+	case "remove_co_moderator":
+		delete(h.coModerators, target)
+		notice = "A co-moderator has been removed."
+	}
+
+	c.log.Info("Moderators changed", "action", msg.Type, "target_player_id", target, "co_moderators", len(h.coModerators))
+
+	h.moderatorsChangedLocked(notice)
+}
+
+// promoteSpectatorLocked lets a spectator join as a player, even if the
+// lobby is locked or needs a PIN. Spectators can only be promoted before the
+// game starts.
+func (h *Hub) promoteSpectatorLocked(c *Client, msg ClientMessage) {
+	var target *Client
+	for client := range h.clients {
+		if msg.Target != "" && h.deviceCode(client.playerID) == strings.ToUpper(msg.Target) {
+			target = client
+			break
+		}
+	}
+
+	problem := ""
+	switch {
+	case h.gameStarted:
+		problem = "Spectators can only be promoted before the game starts."
+	case target == nil:
+		problem = "That device is no longer connected."
+	case !h.isSpectatorLocked(target.playerID):
+		problem = "That device is not a spectator."
+	}
+
+	if problem != "" {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "moderator_error",
+			Message: problem,
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		return
+	}
+
+	delete(h.spectators, target.playerID)
+	h.grants[target.playerID] = inviteGrant{
+		role: rolePlayer,
+		team: h.grants[target.playerID].team,
+	}
+
+	c.log.Info("Spectator promoted", "target_player_id", target.playerID)
+
+	h.sendToPlayerLocked(target.playerID, h.sessionInfoLocked(target))
+	h.sendToPlayerLocked(target.playerID, SimpleMessage{
+		Type:    "promoted",
+		Message: "The moderator has invited you to play.",
+	})
+	h.sendModeratorViewLocked()
+}
+
+// moderatorsChangedLocked tells every client about a change of moderators,
+// with a fresh session_info reflecting their role.
+func (h *Hub) moderatorsChangedLocked(notice string) {
+	for client := range h.clients {
+		select {
+		case client.send <- h.sessionInfoLocked(client):
+		default:
+			h.dropClientLocked(client)
+			continue
+		}
+
+		select {
+		case client.send <- SimpleMessage{
+			Type:    "moderators_changed",
+			Message: notice,
+		}:
+		default:
+			h.dropClientLocked(client)
+		}
+	}
+
+	// Only moderators may see the list before the game starts.
+	h.broadcastCelebritiesLocked()
+	h.sendModeratorViewLocked()
+}
+
+// Ways of choosing a new host when the host is gone for the grace period.
+const (
+	failoverOff              = "off"
+	failoverCoModerator      = "co-moderator"
+	failoverLongestConnected = "longest-connected"
+	failoverVote             = "vote"
+)
+
+// moderatorVoteDuration is how long players have to vote for a new host.
+const moderatorVoteDuration = 30 * time.Second
+
+// moderatorVote is held among connected players to choose a new host.
+type moderatorVote struct {
+	candidates []string          // PlayerIDs, longest connected first
+	votes      map[string]string // voter PlayerID -> candidate PlayerID
+	endsAt     time.Time
+}
+
+// scheduleFailover passes the host role on if the host has not reconnected
+// within the grace period. gen identifies the disconnection, so that an
+// earlier timer does not cut short the grace period after a reconnection.
+func (h *Hub) scheduleFailover(cfg *Config, hostID string, gen int) {
+	time.Sleep(cfg.moderatorGracePeriod)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failoverGen != gen || h.moderatorPlayerID != hostID || h.connectedLocked(hostID) {
+		return
+	}
+
+	h.log.Info("Host disconnected for grace period", "host_player_id", hostID, "failover", cfg.moderatorFailover)
+
+	h.failoverLocked(cfg)
+}
+
+func (h *Hub) retryFailover(cfg *Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failoverPending {
+		h.failoverLocked(cfg)
+	}
+}
+
+// failoverLocked passes the host role to a co-moderator if one is connected,
+// and otherwise as configured. If nobody can take over, it is retried when
+// somebody who is part of the game reconnects.
+func (h *Hub) failoverLocked(cfg *Config) {
+	h.failoverPending = false
+
+	if id := h.longestConnectedLocked(func(id string) bool { return h.coModerators[id] }); id != "" {
+		h.takeOverLocked(id)
+		return
+	}
+
+	switch cfg.moderatorFailover {
+	case failoverCoModerator:
+		h.failoverPending = true
+		return
+	case failoverVote:
+		if h.startVoteLocked() {
+			return
+		}
+	}
+
+	id := h.successorLocked()
+	if id == "" {
+		h.failoverPending = true
+		return
+	}
+
+	h.takeOverLocked(id)
+}
+
+// successorLocked picks a new host without a vote: the longest-connected
+// spectator, since spectators are not playing, or failing that the
+// longest-connected player. Devices which have not joined the game, such as
+// somebody who has only opened the link, are never chosen.
+func (h *Hub) successorLocked() string {
+	if id := h.longestConnectedLocked(func(id string) bool { return h.spectators[id] && !h.isPlayerLocked(id) }); id != "" {
+		return id
+	}
+
+	return h.longestConnectedLocked(h.isPlayerLocked)
+}
+
+// canSucceedLocked reports whether a cookie could take over from an absent
+// host, so that only they trigger a retry when they connect.
+func (h *Hub) canSucceedLocked(playerID string) bool {
+	return h.coModerators[playerID] || h.spectators[playerID] || h.isPlayerLocked(playerID)
+}
+
+// longestConnectedLocked returns the cookie, other than the host, which has
+// been connected the longest among those accepted by keep.
+func (h *Hub) longestConnectedLocked(keep func(playerID string) bool) string {
+	var (
+		best  string
+		since time.Time
+	)
+
+	for c := range h.clients {
+		if c.playerID == "" || c.playerID == h.moderatorPlayerID || !keep(c.playerID) {
+			continue
+		}
+
+		if best == "" || c.connectedAt.Before(since) {
+			best, since = c.playerID, c.connectedAt
+		}
+	}
+
+	return best
+}
+
+// takeOverLocked makes a cookie the interim host, until the host returns.
+func (h *Hub) takeOverLocked(playerID string) {
+	if h.awayHost == "" {
+		h.awayHost = h.moderatorPlayerID
+	}
+	h.awayHostCoMod = h.coModerators[playerID]
+
+	delete(h.coModerators, playerID)
+	h.moderatorPlayerID = playerID
+	h.vote = nil
+
+	h.log.Info("Moderator failed over", "host_player_id", h.awayHost, "new_player_id", playerID)
+
+	text := fmt.Sprintf("The host has disconnected, so %s is now the moderator.", h.describeLocked(playerID))
+	if h.isPlayerLocked(playerID) {
+		text += " As they are playing, who picked each celebrity stays hidden from them."
+	}
+
+	h.moderatorsChangedLocked(text)
+}
+
+// hostReturnedLocked gives the host their rights back when they reconnect,
+// and cancels any vote for a replacement.
+func (h *Hub) hostReturnedLocked(c *Client) {
+	switch {
+	case h.awayHost != "" && c.playerID == h.awayHost:
+		interim := h.moderatorPlayerID
+		h.moderatorPlayerID = h.awayHost
+		h.awayHost = ""
+		if h.awayHostCoMod {
+			h.coModerators[interim] = true
+		}
+	case c.playerID == h.moderatorPlayerID && (h.vote != nil || h.failoverPending):
+	default:
+		return
+	}
+
+	h.vote = nil
+	h.failoverPending = false
+
+	c.log.Info("Host reconnected")
+
+	h.moderatorsChangedLocked("The host has reconnected and is the moderator again.")
+}
+
+// describeLocked names a cookie for announcements.
+func (h *Hub) describeLocked(playerID string) string {
+	for _, p := range h.players {
+		if p.PlayerID == playerID {
+			return p.Username
+		}
+	}
+
+	return "device " + h.deviceCode(playerID)
+}
+
+// startVoteLocked asks connected players to vote for one of themselves as
+// the new host, reporting false if fewer than two are connected, in which
+// case there is nothing to vote on.
+func (h *Hub) startVoteLocked() bool {
+	var candidates []string
+	for {
+		id := h.longestConnectedLocked(func(id string) bool {
+			return !slices.Contains(candidates, id) && slices.ContainsFunc(h.players, func(p Player) bool { return p.PlayerID == id })
+		})
+		if id == "" {
+			break
+		}
+		candidates = append(candidates, id)
+	}
+
+	if len(candidates) < 2 {
+		return false
+	}
+
+	vote := &moderatorVote{
+		candidates: candidates,
+		votes:      make(map[string]string),
+		endsAt:     time.Now().Add(moderatorVoteDuration),
+	}
+	h.vote = vote
+
+	names := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		names = append(names, h.describeLocked(id))
+	}
+
+	h.log.Info("Moderator vote started", "candidates", len(candidates))
+
+	msg := ModeratorVoteMessage{
+		Type:       "moderator_vote",
+		Candidates: names,
+		EndsAt:     vote.endsAt,
+	}
+
+	for client := range h.clients {
+		select {
+		case client.send <- msg:
+		default:
+			h.dropClientLocked(client)
+		}
+	}
+
+	go func() {
+		time.Sleep(moderatorVoteDuration)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.vote == vote {
+			h.finishVoteLocked()
+		}
+	}()
+
+	return true
+}
+
+func (h *Hub) handleVote(vr voteRequest) {
+	c := vr.client
+	msg := vr.msg
+
+	defer vr.span.End()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.vote == nil || !slices.ContainsFunc(h.players, func(p Player) bool { return p.PlayerID == c.playerID }) {
+		return
+	}
+
+	var choice string
+	for _, id := range h.vote.candidates {
+		if h.describeLocked(id) == msg.TargetUsername {
+			choice = id
+			break
+		}
+	}
+	if choice == "" {
+		return
+	}
+
+	h.vote.votes[c.playerID] = choice
+
+	// Finish early once every connected player has voted.
+	for client := range h.clients {
+		if _, voted := h.vote.votes[client.playerID]; !voted && slices.ContainsFunc(h.players, func(p Player) bool { return p.PlayerID == client.playerID }) {
+			return
+		}
+	}
+
+	h.finishVoteLocked()
+}
+
+// finishVoteLocked makes the connected candidate with the most votes the
+// host. Ties, and a vote nobody took part in, go to whoever has been
+// connected the longest.
+func (h *Hub) finishVoteLocked() {
+	tally := make(map[string]int)
+	for _, id := range h.vote.votes {
+		tally[id]++
+	}
+
+	winner := ""
+	for _, id := range h.vote.candidates {
+		if !h.connectedLocked(id) {
+			continue
+		}
+		if winner == "" || tally[id] > tally[winner] {
+			winner = id
+		}
+	}
+
+	h.log.Info("Moderator vote finished", "votes", len(h.vote.votes))
+
+	h.vote = nil
+
+	if winner == "" {
+		winner = h.successorLocked()
+	}
+	if winner == "" {
+		h.failoverPending = true
+		return
+	}
+
+	h.takeOverLocked(winner)
+}
+
+func (h *Hub) sendModeratorViewLocked() {
+	var modClients []*Client
+	for c := range h.clients {
+		if h.isModeratorLocked(c.playerID) {
+			modClients = append(modClients, c)
+		}
+	}
+	if len(modClients) == 0 {
+		return
+	}
+
+	players := make([]ModeratorPlayer, 0, len(h.players))
+	for _, p := range h.players {
+		players = append(players, ModeratorPlayer{
+			Username:  p.Username,
+			Celebrity: p.Celebrity,
+			Team:      p.Team,
+		})
+	}
+
+	pending := make([]ModeratorPlayer, 0, len(h.pending))
+	for _, p := range h.pending {
+		pending = append(pending, ModeratorPlayer{
+			Username:  p.Username,
+			Celebrity: p.Celebrity,
+			Team:      h.grants[p.PlayerID].team,
+		})
+	}
+	slices.SortFunc(pending, func(a, b ModeratorPlayer) int {
+		return strings.Compare(a.Username, b.Username)
+	})
+
+	msg := ModeratorViewMessage{
+		Type:        "moderator_view",
+		Players:     players,
+		Pending:     pending,
+		Devices:     h.devicesLocked(),
+		Spectators:  h.spectatorCountLocked(),
+		Displays:    h.displayCountLocked(),
+		PINSet:      h.pin != "",
+		FailedPINs:  h.failedPINs,
+		LobbyLocked: h.lobbyLocked,
+		CreatedAt:   h.createdAt,
+		LastActive:  h.lastActive,
+	}
+
+	for _, c := range modClients {
+		view := msg
+		if !h.seesMappingLocked(c.playerID) {
+			view = hideCelebrities(msg, h.idToUsernameLocked()[c.playerID])
+		}
+
+		select {
+		case c.send <- view:
+		default:
+			h.dropClientLocked(c)
+		}
+	}
+}
+
+// hideCelebrities returns a copy of a moderator view without the celebrities
+// of anyone but the named player.
+func hideCelebrities(msg ModeratorViewMessage, username string) ModeratorViewMessage {
+	hide := func(players []ModeratorPlayer) []ModeratorPlayer {
+		hidden := make([]ModeratorPlayer, len(players))
+		for i, p := range players {
+			if p.Username != username {
+				p.Celebrity = ""
+			}
+			hidden[i] = p
+		}
+
+		return hidden
+	}
+
+	msg.Players = hide(msg.Players)
+	msg.Pending = hide(msg.Pending)
+	msg.CelebritiesHidden = true
+
+	return msg
+}
+
+func (h *Hub) spectatorCountLocked() int {
+	seen := make(map[string]bool)
+	for c := range h.clients {
+		if h.isSpectatorLocked(c.playerID) {
+			seen[c.playerID] = true
+		}
+	}
+
+	return len(seen)
+}
+
+// devicesLocked lists the connected cookies which have not joined as
+// players, in the order they connected.
+func (h *Hub) devicesLocked() []ModeratorDevice {
+	byID := make(map[string]*ModeratorDevice)
+
+	for c := range h.clients {
+		if h.isPlayerLocked(c.playerID) {
+			continue
+		}
+
+		if d, ok := byID[c.playerID]; ok {
+			if c.connectedAt.Before(d.ConnectedAt) {
+				d.ConnectedAt = c.connectedAt
+			}
+			continue
+		}
+
+		role := "viewer"
+		switch {
+		case c.playerID == h.moderatorPlayerID:
+			role = "host"
+		case h.coModerators[c.playerID]:
+			role = "co_moderator"
+		case h.spectators[c.playerID]:
+			role = "spectator"
+		}
+
+		byID[c.playerID] = &ModeratorDevice{
+			Code:        h.deviceCode(c.playerID),
+			Role:        role,
+			ConnectedAt: c.connectedAt,
+		}
+	}
+
+	devices := make([]ModeratorDevice, 0, len(byID))
+	for _, d := range byID {
+		devices = append(devices, *d)
+	}
+	slices.SortFunc(devices, func(a, b ModeratorDevice) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+
+	return devices
+}
+
+// displayCodeAlphabet leaves out characters which are easily confused on a
+// screen across the room.
+const displayCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newDisplayCodeLocked returns a pairing code not shown by any other display
+// of the game.
+func (h *Hub) newDisplayCodeLocked() string {
+	for {
+		buf := make([]byte, 6)
+		if _, err := rand.Read(buf); err != nil {
+			panic("crypto/rand failure: " + err.Error())
+		}
+		for i := range buf {
+			buf[i] = displayCodeAlphabet[int(buf[i])%len(displayCodeAlphabet)]
+		}
+		code := string(buf)
+
+		taken := false
+		for d := range h.displays {
+			if d.code == code {
+				taken = true
+				break
+			}
+		}
+
+		if !taken {
+			return code
+		}
+	}
+}
+
+// addDisplay attaches a display to the game. A display which presents a
+// valid token from an earlier pairing is paired straight away; any other is
+// sent a code for a moderator to enter.
+func (h *Hub) addDisplay(cfg *Config, d *Display) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if d.token != "" {
+		inv, err := decodeInvite(cfg.cookieKeys(), d.token, time.Now())
+		if err == nil && inv.Kind == inviteDisplay && inv.Game == h.game+"/"+h.id && inv.Created == h.createdAt.UnixNano() {
+			if h.displayCountLocked() >= maxDisplays {
+				return errTooManyDisplays
+			}
+
+			d.log.Info("Display reconnected")
+			h.displays[d] = true
+			d.paired = true
+			d.send <- DisplayPairedMessage{
+				Type:  "display_paired",
+				Token: d.token,
+			}
+			d.send <- h.displayCelebritiesLocked()
+			d.send <- h.currentGameStateLocked()
+			h.sendModeratorViewLocked()
+
+			return nil
+		}
+	}
+
+	waiting := 0
+	for other := range h.displays {
+		if !other.paired {
+			waiting++
+		}
+	}
+	if waiting >= maxUnpairedDisplays {
+		return errTooManyUnpaired
+	}
+
+	h.displays[d] = true
+
+	d.code = h.newDisplayCodeLocked()
+	d.send <- DisplayPairingMessage{
+		Type: "display_pairing",
+		Code: d.code,
+	}
+
+	go h.expireDisplay(d)
+
+	return nil
+}
+
+// expireDisplay disconnects a display which has not been paired within
+// displayPairingTimeout.
+func (h *Hub) expireDisplay(d *Display) {
+	time.Sleep(displayPairingTimeout)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.displays[d]; !ok || d.paired {
+		return
+	}
+
+	d.log.Info("Display pairing expired", "reason", "timeout")
+	h.closeDisplayLocked(d, errPairingExpired)
+}
 
-	case "restart_game":
-		h.restartGameLocked()
+// closeDisplayLocked detaches a display, telling it why so that it does not
+// try to reconnect.
+func (h *Hub) closeDisplayLocked(d *Display, err error) {
+	select {
+	case d.send <- SimpleMessage{
+		Type:    "error",
+		Message: err.Error(),
+	}:
+	default:
 	}
+
+	delete(h.displays, d)
+	close(d.send)
 }
 
-func (h *Hub) sendModeratorViewLocked() {
-	if h.moderatorPlayerID == "" {
+// removeDisplay detaches a display from the game, if it is still attached.
+func (h *Hub) removeDisplay(d *Display) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.displays[d]; !ok {
 		return
 	}
 
-	var modClient *Client
-	for c := range h.clients {
-		if c.playerID == h.moderatorPlayerID {
-			modClient = c
+	delete(h.displays, d)
+	close(d.send)
+
+	if d.paired {
+		h.sendModeratorViewLocked()
+	}
+}
+
+// pairDisplayLocked pairs the display showing the code a moderator entered,
+// and sends it the public state of the game.
+func (h *Hub) pairDisplayLocked(cfg *Config, c *Client, msg ClientMessage) {
+	code := strings.ToUpper(strings.TrimSpace(msg.Code))
+
+	var d *Display
+	for other := range h.displays {
+		if !other.paired && code != "" && other.code == code {
+			d = other
 			break
 		}
 	}
-	if modClient == nil {
+	if d == nil {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "moderator_error",
+			Message: "No display is showing that code.",
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
 		return
 	}
 
-	players := make([]ModeratorPlayer, 0, len(h.players))
-	for _, p := range h.players {
-		players = append(players, ModeratorPlayer{
-			Username:  p.Username,
-			Celebrity: p.Celebrity,
-		})
+	if h.displayCountLocked() >= maxDisplays {
+		select {
+		case c.send <- SimpleMessage{
+			Type:    "moderator_error",
+			Message: errTooManyDisplays.Error(),
+		}:
+		default:
+			h.dropClientLocked(c)
+		}
+		return
 	}
 
-	msg := ModeratorViewMessage{
-		Type:        "moderator_view",
-		Players:     players,
-		LobbyLocked: h.lobbyLocked,
-		CreatedAt:   h.createdAt,
-		LastActive:  h.lastActive,
+	id, err := newInviteID()
+	if err != nil {
+		c.log.Error("Failed to pair display", "error", err)
+		return
+	}
+
+	token, err := encodeInvite(cfg.cookieKeys()[0], &invite{
+		Kind:    inviteDisplay,
+		Game:    h.game + "/" + h.id,
+		Created: h.createdAt.UnixNano(),
+		ID:      id,
+		Expires: time.Now().Add(displayTokenLifetime).Unix(),
+	})
+	if err != nil {
+		c.log.Error("Failed to pair display", "error", err)
+		return
+	}
+
+	c.log.Info("Display paired", "display", d.code)
+
+	d.paired = true
+	d.code = ""
+
+	h.sendToDisplayLocked(d, DisplayPairedMessage{
+		Type:  "display_paired",
+		Token: token,
+	})
+	h.sendToDisplayLocked(d, h.displayCelebritiesLocked())
+	h.sendToDisplayLocked(d, h.currentGameStateLocked())
+
+	h.sendModeratorViewLocked()
+}
+
+// displayCelebritiesLocked returns the celebrity list as displays see it,
+// which like players' is empty until the game starts.
+func (h *Hub) displayCelebritiesLocked() CelebrityListMessage {
+	celebs := []string{}
+	if h.gameStarted {
+		celebs = h.currentCelebritiesLocked()
+	}
+
+	return CelebrityListMessage{
+		Type:        "celebrity_list",
+		Celebrities: celebs,
+	}
+}
+
+// sendToDisplayLocked sends a message to a display, disconnecting it if its
+// send buffer is full.
+func (h *Hub) sendToDisplayLocked(d *Display, msg outgoingMessage) {
+	if _, ok := h.displays[d]; !ok {
+		return
 	}
 
 	select {
-	case modClient.send <- msg:
+	case d.send <- msg:
 	default:
-		delete(h.clients, modClient)
-		close(modClient.send)
+		delete(h.displays, d)
+		close(d.send)
+
+		metrics.droppedClients.inc(h.game)
+	}
+}
+
+// sendToDisplaysLocked sends public state to every paired display.
+func (h *Hub) sendToDisplaysLocked(msg outgoingMessage) {
+	for d := range h.displays {
+		if d.paired {
+			h.sendToDisplayLocked(d, msg)
+		}
+	}
+}
+
+func (h *Hub) displayCountLocked() int {
+	n := 0
+	for d := range h.displays {
+		if d.paired {
+			n++
+		}
 	}
+
+	return n
 }
 
 func (h *Hub) closeAll() {
@@ -807,70 +2573,94 @@ func (h *Hub) closeAll() {
 		_ = c.conn.Close()
 		delete(h.clients, c)
 	}
+
+	for d := range h.displays {
+		close(d.send)
+		_ = d.conn.Close()
+		delete(h.displays, d)
+	}
 }
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Origins are checked by serveWSForManager before the game is looked
+	// up, so that rejected requests do not create games.
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
-const playerCookieName = "partybox_id"
-
-func getOrSetPlayerID(w http.ResponseWriter, r *http.Request) string {
-	if c, err := r.Cookie(playerCookieName); err == nil && c.Value != "" {
-		return c.Value
-	}
-
-	buf := make([]byte, 16)
-	if _, err := rand.Read(buf); err != nil {
-		log.Println("rand.Read error:", err)
-		return ""
-	}
-	id := hex.EncodeToString(buf)
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     playerCookieName,
-		Value:    id,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
-
-	return id
-}
-
 type GameManager struct {
-	mu          sync.Mutex
-	hubs        map[string]*Hub
-	idleTimeout time.Duration
+	name          string
+	createLimiter *rateLimiter
+	pinLimiter    *rateLimiter
+	mu            sync.Mutex
+	hubs          map[string]*Hub
 }
 
-func newGameManager(idleTimeout time.Duration) *GameManager {
+func newGameManager(cfg *Config, name string) *GameManager {
 	gm := &GameManager{
-		hubs:        make(map[string]*Hub),
-		idleTimeout: idleTimeout,
-	}
-	if idleTimeout > 0 {
-		go gm.reaperLoop()
+		name:          name,
+		hubs:          make(map[string]*Hub),
+		createLimiter: newRateLimiter("games", func() rateLimit { return cfg.limits().Games }),
+		pinLimiter:    newRateLimiter("pin", func() rateLimit { return cfg.limits().PIN }),
 	}
+	go gm.reaperLoop(cfg)
 	return gm
 }
 
-func (gm *GameManager) getHub(cfg *Config, gameID string) *Hub {
+// getHub returns the game with the given ID, creating it if necessary. New
+// games count against the rate limit for the creating client's address.
+func (gm *GameManager) getHub(cfg *Config, gameID, ip string) (*Hub, error) {
 	gm.mu.Lock()
 	defer gm.mu.Unlock()
 
 	if hub, ok := gm.hubs[gameID]; ok {
-		return hub
+		return hub, nil
+	}
+
+	if err := quota.acquire(cfg, ip); err != nil {
+		return nil, err
+	}
+
+	if ok, wait := gm.createLimiter.allow(ip); !ok {
+		quota.release(ip)
+
+		return nil, &rateLimitError{wait: wait}
 	}
 
-	hub := newHub(gameID)
+	hub := newHub(gm.name, gameID, ip)
+	hub.pinLimiter = gm.pinLimiter
 	gm.hubs[gameID] = hub
+	metrics.gamesCreated.inc(gm.name)
+	slog.Info("Created game", "game_type", gm.name, "game_id", gameID, "ip", ip)
 	go hub.run(cfg)
-	return hub
+	return hub, nil
+}
+
+// lookup returns the game with the given ID, without creating it.
+func (gm *GameManager) lookup(gameID string) (*Hub, bool) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	hub, ok := gm.hubs[gameID]
+
+	return hub, ok
+}
+
+// counts returns the number of active hubs and connected clients.
+func (gm *GameManager) counts() (hubs, clients int) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	for _, hub := range gm.hubs {
+		hub.mu.RLock()
+		clients += len(hub.clients)
+		hub.mu.RUnlock()
+	}
+
+	return len(gm.hubs), clients
 }
 
 func (gm *GameManager) newGameID() string {
@@ -896,10 +2686,21 @@ func (gm *GameManager) newGameID() string {
 	}
 }
 
-func (gm *GameManager) reaperLoop() {
-	ticker := time.NewTicker(gm.idleTimeout / 2)
-	for range ticker.C {
-		cutoff := time.Now().Add(-gm.idleTimeout)
+// reaperLoop removes idle games. The session timeout is re-read on every
+// pass, so changes from a config reload take effect without a restart.
+func (gm *GameManager) reaperLoop(cfg *Config) {
+	const maxInterval = time.Minute
+
+	for {
+		idleTimeout := cfg.gameSettings(gm.name).SessionTimeout
+		if idleTimeout <= 0 {
+			time.Sleep(maxInterval)
+			continue
+		}
+
+		time.Sleep(min(idleTimeout/2, maxInterval))
+
+		cutoff := time.Now().Add(-idleTimeout)
 
 		gm.mu.Lock()
 		for id, hub := range gm.hubs {
@@ -909,6 +2710,8 @@ func (gm *GameManager) reaperLoop() {
 
 			if last.Before(cutoff) {
 				delete(gm.hubs, id)
+				quota.release(hub.creatorIP)
+				metrics.gamesReaped.inc(gm.name)
 				go hub.closeAll()
 			}
 		}
@@ -924,73 +2727,301 @@ func serveWSForManager(cfg *Config, gm *GameManager) httprouter.Handle {
 			return
 		}
 
-		playerID := getOrSetPlayerID(w, r)
+		if !originAllowed(cfg, r) {
+			slog.Warn("Rejected WebSocket origin", "game_type", gm.name, "game_id", gameID, "origin", r.Header.Get("Origin"), "ip", realIP(r))
+			metrics.rejectedOrigins.inc(gm.name)
+
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		playerID := getOrSetPlayerID(cfg, w, r)
 		if playerID == "" {
 			http.Error(w, "unable to assign player id", http.StatusInternalServerError)
 			return
 		}
 
-		hub := gm.getHub(cfg, gameID)
+		if err := bans.check(gm.name+"/"+gameID, playerID, requestClient(r).addr); err != nil {
+			slog.Warn("Refused banned WebSocket connection", "game_type", gm.name, "game_id", gameID, "player_id", playerID, "ip", realIP(r))
+			rejectWebSocket(w, r, err)
+			return
+		}
+
+		hub, err := gm.getHub(cfg, gameID, remoteHost(r))
+		if err != nil {
+			slog.Warn("Refused WebSocket connection", "game_type", gm.name, "game_id", gameID, "player_id", playerID, "ip", realIP(r), "error", err)
+			rejectWebSocket(w, r, err)
+			return
+		}
 
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Println("upgrade error:", err)
+			slog.Warn("WebSocket upgrade failed", "game_type", gm.name, "game_id", gameID, "player_id", playerID, "ip", realIP(r), "error", err)
 			return
 		}
+		conn.SetReadLimit(maxMessageSize)
+
+		remoteIP := realIP(r)
 
 		client := &Client{
 			conn:     conn,
-			send:     make(chan any, 8),
+			send:     make(chan outgoingMessage, 8),
+			commands: newTokenBucket(cfg.limits().Commands),
+			admitted: make(chan bool, 1),
 			playerID: playerID,
+			remoteIP: remoteIP,
+			addr:     requestClient(r).addr,
+			invite:   r.URL.Query().Get(inviteParam),
+			log:      hub.log.With("player_id", playerID, "ip", remoteIP),
+
+			connectedAt: time.Now(),
 		}
 
+		client.ctx, client.span = tracer.Start(r.Context(), "websocket.session", spanKindInternal,
+			"game.type", gm.name,
+			"game.id", gameID,
+			"player.id", playerID,
+		)
+
+		client.log.Debug("Client connected")
+
 		hub.register <- client
 
-		go client.writePump()
-		client.readPump(hub)
+		go client.writePump(hub)
+		client.readPump(cfg, hub)
+	}
+}
+
+// rejectWebSocket completes the upgrade only to report why the connection is
+// refused, since browsers do not expose the response to a failed upgrade.
+func rejectWebSocket(w http.ResponseWriter, r *http.Request, reason error) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
 	}
+	defer conn.Close()
+
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	_ = conn.WriteJSON(SimpleMessage{
+		Type:    "error",
+		Message: reason.Error(),
+	})
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ""))
 }
 
-func (c *Client) readPump(h *Hub) {
+func (c *Client) readPump(cfg *Config, h *Hub) {
 	defer func() {
 		h.unreg <- c
 		_ = c.conn.Close()
+		c.log.Info("WebSocket session closed",
+			"duration", time.Since(c.connectedAt).Round(time.Millisecond),
+			"messages_received", c.received.Load(),
+			"messages_sent", c.sent.Load(),
+		)
+		c.span.SetAttributes(
+			"websocket.messages_received", c.received.Load(),
+			"websocket.messages_sent", c.sent.Load(),
+		)
+		c.span.End()
 	}()
 
+	// A refused client is only sent the reason, after which writePump
+	// closes the connection. Until then, anything it sends is discarded.
+	admitted := <-c.admitted
+
 	for {
 		var msg ClientMessage
 		if err := c.conn.ReadJSON(&msg); err != nil {
 			return
 		}
 
-		switch msg.Type {
-		case "join":
-			h.joins <- joinRequest{
-				client: c,
-				msg:    msg,
-			}
-		case "lock_lobby", "kick", "start_game", "restart_game":
-			h.mods <- modCommand{
-				client: c,
-				msg:    msg,
-			}
-		case "guess":
-			h.guesses <- guessRequest{
-				client: c,
-				msg:    msg,
-			}
-		default:
+		if !admitted {
+			continue
+		}
+
+		c.received.Add(1)
+
+		c.commands.setLimit(cfg.limits().Commands)
+		if ok, _ := c.commands.take(time.Now()); !ok {
+			metrics.rateLimited.inc("commands")
+			h.notify(c, SimpleMessage{
+				Type:    "rate_limited",
+				Message: "You are sending commands too quickly. Please slow down.",
+			})
+			continue
+		}
+
+		queue, ok := clientCommands[msg.Type]
+		if !ok {
+			metrics.messagesIn.inc(h.game, "unknown")
+			continue
 		}
+
+		metrics.messagesIn.inc(h.game, msg.Type)
+
+		queue(h, c, msg, c.startCommandSpan(h, msg.Type))
 	}
 }
 
-func (c *Client) writePump() {
+// clientCommands maps each message type a client may send to the hub
+// channel which handles it. Anything else is counted as unknown and ignored.
+var clientCommands = map[string]func(h *Hub, c *Client, msg ClientMessage, span *Span){
+	"join":     (*Hub).queueJoin,
+	"spectate": (*Hub).queueJoin,
+
+	"lock_lobby":          (*Hub).queueModCommand,
+	"set_pin":             (*Hub).queueModCommand,
+	"kick":                (*Hub).queueModCommand,
+	"approve_join":        (*Hub).queueModCommand,
+	"reject_join":         (*Hub).queueModCommand,
+	"create_invite":       (*Hub).queueModCommand,
+	"create_reclaim_link": (*Hub).queueModCommand,
+	"transfer_moderator":  (*Hub).queueModCommand,
+	"add_co_moderator":    (*Hub).queueModCommand,
+	"remove_co_moderator": (*Hub).queueModCommand,
+	"promote_spectator":   (*Hub).queueModCommand,
+	"pair_display":        (*Hub).queueModCommand,
+	"start_game":          (*Hub).queueModCommand,
+	"restart_game":        (*Hub).queueModCommand,
+
+	"guess":          (*Hub).queueGuess,
+	"vote_moderator": (*Hub).queueVote,
+}
+
+func (h *Hub) queueJoin(c *Client, msg ClientMessage, span *Span) {
+	h.joins <- joinRequest{client: c, msg: msg, span: span}
+}
+
+func (h *Hub) queueModCommand(c *Client, msg ClientMessage, span *Span) {
+	h.mods <- modCommand{client: c, msg: msg, span: span}
+}
+
+func (h *Hub) queueGuess(c *Client, msg ClientMessage, span *Span) {
+	h.guesses <- guessRequest{client: c, msg: msg, span: span}
+}
+
+func (h *Hub) queueVote(c *Client, msg ClientMessage, span *Span) {
+	h.votes <- voteRequest{client: c, msg: msg, span: span}
+}
+
+// startCommandSpan traces a hub command from receipt until the hub has
+// finished processing it, including any time spent queued.
+func (c *Client) startCommandSpan(h *Hub, command string) *Span {
+	_, span := tracer.Start(c.ctx, h.game+"."+command, spanKindInternal,
+		"game.type", h.game,
+		"game.id", h.id,
+		"player.id", c.playerID,
+		"game.command", command,
+	)
+
+	return span
+}
+
+func (c *Client) writePump(h *Hub) {
 	defer c.conn.Close()
 
 	for msg := range c.send {
 		if err := c.conn.WriteJSON(msg); err != nil {
 			return
 		}
+		c.sent.Add(1)
+		metrics.messagesOut.inc(h.game, messageType(msg))
+	}
+}
+
+// serveDisplayWSForManager connects a display to an existing game. Displays
+// carry no player cookie, so opening one never creates a game or claims a
+// role in it.
+func serveDisplayWSForManager(cfg *Config, gm *GameManager) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		gameID := ps.ByName("gameid")
+		if gameID == "" {
+			http.Error(w, "missing game id", http.StatusBadRequest)
+			return
+		}
+
+		if !originAllowed(cfg, r) {
+			slog.Warn("Rejected WebSocket origin", "game_type", gm.name, "game_id", gameID, "origin", r.Header.Get("Origin"), "ip", realIP(r))
+			metrics.rejectedOrigins.inc(gm.name)
+
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if err := bans.check(gm.name+"/"+gameID, "", requestClient(r).addr); err != nil {
+			slog.Warn("Refused banned display", "game_type", gm.name, "game_id", gameID, "ip", realIP(r))
+			rejectWebSocket(w, r, err)
+			return
+		}
+
+		hub, ok := gm.lookup(gameID)
+		if !ok {
+			rejectWebSocket(w, r, errNoSuchGame)
+			return
+		}
+
+		remoteIP := realIP(r)
+
+		d := &Display{
+			send:  make(chan outgoingMessage, 8),
+			token: r.URL.Query().Get(displayParam),
+			log:   hub.log.With("display", true, "ip", remoteIP),
+
+			connectedAt: time.Now(),
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			slog.Warn("WebSocket upgrade failed", "game_type", gm.name, "game_id", gameID, "ip", remoteIP, "error", err)
+			return
+		}
+		conn.SetReadLimit(512)
+		d.conn = conn
+
+		if err := hub.addDisplay(cfg, d); err != nil {
+			d.log.Warn("Refused display", "error", err)
+
+			_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+			_ = conn.WriteJSON(SimpleMessage{
+				Type:    "error",
+				Message: err.Error(),
+			})
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ""))
+			_ = conn.Close()
+			return
+		}
+
+		d.log.Debug("Display connected")
+
+		go d.writePump(hub)
+		d.readPump(hub)
+	}
+}
+
+// readPump discards anything a display sends, and detaches it once the
+// connection closes.
+func (d *Display) readPump(h *Hub) {
+	defer func() {
+		h.removeDisplay(d)
+		_ = d.conn.Close()
+		d.log.Info("Display disconnected", "duration", time.Since(d.connectedAt).Round(time.Millisecond))
+	}()
+
+	for {
+		if _, _, err := d.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (d *Display) writePump(h *Hub) {
+	defer d.conn.Close()
+
+	for msg := range d.send {
+		if err := d.conn.WriteJSON(msg); err != nil {
+			return
+		}
+		metrics.messagesOut.inc(h.game, messageType(msg))
 	}
 }
 
@@ -1022,17 +3053,13 @@ func (h *Hub) currentGameStateLocked() GameStateMessage {
 
 	winnerName := ""
 	if !h.gameStarted {
-		activeCount := 0
-		var lastActiveID string
-		for _, p := range h.players {
-			if h.eliminated[p.PlayerID] {
-				continue
-			}
-			activeCount++
-			lastActiveID = p.PlayerID
+		// A winning team is named after its leader.
+		winners := h.activePlayersLocked()
+		if h.teamsSeeded {
+			winners = h.activeTeamsLocked()
 		}
-		if activeCount == 1 {
-			if name, ok := idToUser[lastActiveID]; ok {
+		if len(winners) == 1 {
+			if name, ok := idToUser[winners[0]]; ok {
 				winnerName = name
 			}
 		}
@@ -1079,40 +3106,35 @@ func (h *Hub) broadcastGameStateLocked() {
 		select {
 		case client.send <- msg:
 		default:
-			delete(h.clients, client)
-			close(client.send)
+			h.dropClientLocked(client)
 		}
 	}
+	h.sendToDisplaysLocked(msg)
 }
 
-func qrHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	gameID := ps.ByName("gameid")
-	if gameID == "" {
-		http.Error(w, "missing game id", http.StatusBadRequest)
-		return
-	}
-
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
-	}
-	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
-		scheme = proto
-	}
+func qrHandler(cfg *Config, path string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		gameID := ps.ByName("gameid")
+		if gameID == "" {
+			http.Error(w, "missing game id", http.StatusBadRequest)
+			return
+		}
 
-	path := strings.TrimSuffix(r.URL.Path, "/qr")
+		link := cfg.absoluteURL(r, cfg.prefix+path+"/"+gameID)
+		if token := r.URL.Query().Get(inviteParam); token != "" {
+			link += "?" + inviteParam + "=" + url.QueryEscape(token)
+		}
 
-	url := scheme + "://" + r.Host + path
+		const qrSize = 320
+		png, err := qrcode.Encode(link, qrcode.Medium, qrSize)
+		if err != nil {
+			http.Error(w, "qr generation failed", http.StatusInternalServerError)
+			return
+		}
 
-	const qrSize = 320
-	png, err := qrcode.Encode(url, qrcode.Medium, qrSize)
-	if err != nil {
-		http.Error(w, "qr generation failed", http.StatusInternalServerError)
-		return
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(png)
 	}
-
-	w.Header().Set("Content-Type", "image/png")
-	_, _ = w.Write(png)
 }
 
 //go:embed celebrity/index.html
@@ -1124,6 +3146,12 @@ var partyboxCSS []byte
 //go:embed celebrity/app.js
 var partyboxJS []byte
 
+//go:embed celebrity/display.html
+var displayHTML []byte
+
+//go:embed celebrity/display.js
+var displayJS []byte
+
 func getIndexHandler(cfg *Config) func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -1131,7 +3159,7 @@ func getIndexHandler(cfg *Config) func(w http.ResponseWriter, r *http.Request, _
 		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
 		securityHeaders(cfg, w)
 
-		_ = getOrSetPlayerID(w, r)
+		_ = getOrSetPlayerID(cfg, w, r)
 
 		_, _ = w.Write(indexHTML)
 	}
@@ -1159,18 +3187,55 @@ func getJsHandler(cfg *Config) func(w http.ResponseWriter, r *http.Request, _ ht
 	}
 }
 
+func getDisplayHandler(cfg *Config) func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		securityHeaders(cfg, w)
+
+		_, _ = w.Write(displayHTML)
+	}
+}
+
+func getDisplayJsHandler(cfg *Config) func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		securityHeaders(cfg, w)
+
+		_, _ = w.Write(displayJS)
+	}
+}
+
 func redirectNewGame(cfg *Config, path string, gm *GameManager) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if err := bans.check("", getOrSetPlayerID(cfg, w, r), requestClient(r).addr); err != nil {
+			slog.Warn("Refused to create game for banned client", "game_type", gm.name, "ip", realIP(r))
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			securityHeaders(cfg, w)
+			w.WriteHeader(http.StatusForbidden)
+
+			_, _ = io.WriteString(w, newPage(cfg, "Forbidden", err.Error()))
+			return
+		}
+
+		// The game itself is only created, and counted against the creation
+		// limits, once somebody connects to it, so that link prefetchers do
+		// not use them up.
 		gameID := gm.newGameID()
-		logf(cfg, "GAMES: Created game %s/%s", path, gameID)
-		http.Redirect(w, r, path+"/"+gameID, http.StatusTemporaryRedirect)
+
+		slog.Debug("Assigned game ID", "game_type", gm.name, "game_id", gameID, "ip", realIP(r))
+		http.Redirect(w, r, cfg.redirectURL(cfg.prefix+path+"/"+gameID), http.StatusTemporaryRedirect)
 	}
 }
 
-func registerCelebrityGame(cfg *Config, path string, mux *httprouter.Router) {
-	gm := newGameManager(cfg.sessionTimeout)
+func registerCelebrityGame(cfg *Config, path string, mux *httprouter.Router) *GameManager {
+	gm := newGameManager(cfg, strings.TrimPrefix(path, "/"))
 
-	mux.GET(path, redirectNewGame(cfg, path, gm))
+	mux.GET(cfg.prefix+path, redirectNewGame(cfg, path, gm))
 
 	mux.GET(cfg.prefix+path+"/:gameid", getIndexHandler(cfg))
 
@@ -1179,5 +3244,11 @@ func registerCelebrityGame(cfg *Config, path string, mux *httprouter.Router) {
 
 	mux.GET(cfg.prefix+path+"/:gameid/ws", serveWSForManager(cfg, gm))
 
-	mux.GET(cfg.prefix+path+"/:gameid/qr", qrHandler)
+	mux.GET(cfg.prefix+path+"/:gameid/qr", qrHandler(cfg, path))
+
+	mux.GET(cfg.prefix+path+"/:gameid/display", getDisplayHandler(cfg))
+	mux.GET(cfg.prefix+"/assets/celebrity/display.js", getDisplayJsHandler(cfg))
+	mux.GET(cfg.prefix+path+"/:gameid/display/ws", serveDisplayWSForManager(cfg, gm))
+
+	return gm
 }
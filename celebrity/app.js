@@ -1,6 +1,9 @@
 (function() {
   const statusEl = document.getElementById('status');
   const gameInfoEl = document.getElementById('game-info');
+  const votePanel = document.getElementById('vote-panel');
+  const voteTextEl = document.getElementById('vote-text');
+  const voteButtons = document.getElementById('vote-buttons');
   const celebsEl = document.getElementById('celebs');
   const userNameEl = document.getElementById('user-name');
 
@@ -9,9 +12,21 @@
   const startBtn = document.getElementById('start-btn');
   const restartBtn = document.getElementById('restart-btn');
   const lockStatusEl = document.getElementById('lock-status');
+  const pinBtn = document.getElementById('pin-btn');
+  const pinStatusEl = document.getElementById('pin-status');
   const playersBody = document.getElementById('players-body');
   const playerCountEl = document.getElementById('player-count');
   const playerWarningEl = document.getElementById('player-warning');
+  const spectatorSummaryEl = document.getElementById('spectator-summary');
+  const pendingSection = document.getElementById('pending-section');
+  const pendingBody = document.getElementById('pending-body');
+  const pendingCountEl = document.getElementById('pending-count');
+  const devicesSection = document.getElementById('devices-section');
+  const devicesBody = document.getElementById('devices-body');
+  const deviceCountEl = document.getElementById('device-count');
+  const inviteBtn = document.getElementById('invite-btn');
+  const reclaimBtn = document.getElementById('reclaim-btn');
+  const pairDisplayBtn = document.getElementById('pair-display-btn');
 
   const guessModal = document.getElementById('guess-modal');
   const guessTextEl = document.getElementById('guess-text');
@@ -22,15 +37,27 @@
   const qrBtn = document.getElementById('qr-btn');
   const qrModal = document.getElementById('qr-modal');
   const qrImage = document.getElementById('qr-image');
+  const qrTitle = document.getElementById('qr-title');
+  const qrText = document.getElementById('qr-text');
+  const qrLink = document.getElementById('qr-link');
   const qrClose = document.getElementById('qr-close');
 
   const newGameBtn = document.getElementById('new-game-btn');
+  const spectateBtn = document.getElementById('spectate-btn');
 
   let username = '';
   let celeb = '';
+  let pin = '';
+  let pinRequired = false;
   let isModerator = false;
+  let celebritiesHidden = false;
+  let isHost = false;
+  let isSpectator = false;
+  let deviceCode = '';
+  let sessionSeen = false;
   let lobbyLocked = false;
   let wasKicked = false;
+  let wasRefused = false;
   let gameStarted = false;
   let currentTurnUser = '';
   let amOut = false;
@@ -38,6 +65,13 @@
   let eliminatedList = [];
   let pendingCelebrity = '';
 
+  // An invite token is passed to the server on the first connection only,
+  // and removed from the address bar so that it is not shared by accident.
+  let inviteToken = new URLSearchParams(location.search).get('invite') || '';
+  if (inviteToken) {
+    history.replaceState(null, '', location.pathname);
+  }
+
   let ws = null;
   let connectAttempts = 0;
   const MAX_CONNECT_ATTEMPTS = 8;
@@ -47,7 +81,8 @@
   function wsURL() {
     const proto = (location.protocol === 'https:') ? 'wss://' : 'ws://';
     const wsPath = location.pathname.replace(/\/$/, '') + '/ws';
-    return proto + location.host + wsPath;
+    const query = inviteToken ? '?invite=' + encodeURIComponent(inviteToken) : '';
+    return proto + location.host + wsPath + query;
   }
 
   function clearWatchdog() {
@@ -104,7 +139,7 @@
           return;
         }
 
-        if (msg.type === 'collision') {
+        if (msg.type === 'collision' || msg.type === 'invalid_field') {
           handleCollision(msg);
           return;
         }
@@ -120,7 +155,52 @@
           return;
         }
 
-        if (msg.type === 'lobby_locked') {
+        if (msg.type === 'joined') {
+          username = msg.username;
+          celeb = msg.celebrity;
+          userNameEl.textContent = username;
+          statusEl.textContent = 'Joined as ' + username + '.';
+          return;
+        }
+
+        if (msg.type === 'pin_required') {
+          statusEl.textContent = msg.message;
+          pin = prompt(msg.message) || '';
+          if (!pin) return;
+          sendJoin();
+          return;
+        }
+
+        if (msg.type === 'join_rejected') {
+          statusEl.textContent = msg.message;
+          promptJoin();
+          return;
+        }
+
+        if (msg.type === 'moderator_vote') {
+          showModeratorVote(msg);
+          return;
+        }
+
+        if (msg.type === 'promoted') {
+          statusEl.textContent = msg.message;
+          promptJoin();
+          return;
+        }
+
+        if (msg.type === 'moderators_changed') {
+          votePanel.style.display = 'none';
+          statusEl.textContent = msg.message;
+          return;
+        }
+
+        if (msg.type === 'invite') {
+          showInvite(msg);
+          return;
+        }
+
+        if (msg.type === 'lobby_locked' || msg.type === 'game_full' || msg.type === 'pending_approval' || msg.type === 'invalid_pin' || msg.type === 'invite_error' ||
+            msg.type === 'moderator_error' || msg.type === 'spectating') {
           statusEl.textContent = msg.message;
           return;
         }
@@ -139,9 +219,14 @@
 
           lobbyLocked = !!msg.lobby_locked;
           updateLockUI();
+          celebritiesHidden = !!msg.celebrities_hidden;
           if (Array.isArray(msg.players)) {
             renderModeratorPlayers(msg.players);
           }
+          renderPendingPlayers(msg.pending || []);
+          renderDevices(msg.devices || []);
+          updateSpectatorSummary(msg.spectators || 0, msg.displays || 0);
+          updatePINStatus(!!msg.pin_set, msg.failed_pin_attempts || 0);
           return;
         }
 
@@ -155,7 +240,13 @@
           return;
         }
 
-        if (msg.type === 'not_your_turn' || msg.type === 'guess_error') {
+        if (msg.type === 'error') {
+          wasRefused = true;
+          statusEl.textContent = msg.message || 'Unable to join this game.';
+          return;
+        }
+
+        if (msg.type === 'not_your_turn' || msg.type === 'guess_error' || msg.type === 'rate_limited') {
           statusEl.textContent = msg.message || '';
           return;
         }
@@ -166,7 +257,7 @@
 
     ws.onclose = function() {
       clearWatchdog();
-      if (wasKicked) {
+      if (wasKicked || wasRefused) {
         return;
       }
       if (connectAttempts >= MAX_CONNECT_ATTEMPTS) {
@@ -178,7 +269,7 @@
     };
 
     ws.onerror = function() {
-      if (!wasKicked) {
+      if (!wasKicked && !wasRefused) {
         statusEl.textContent = 'WebSocket error. Retrying…';
       }
     };
@@ -189,10 +280,18 @@
     safeSend({
       type: 'join',
       username: username,
-      celebrity: celeb
+      celebrity: celeb,
+      pin: pin || undefined
     });
   }
 
+  function updatePINStatus(set, failed) {
+    pinBtn.textContent = set ? 'Change PIN' : 'Set PIN';
+    pinStatusEl.textContent = set
+      ? 'PIN required to join (' + failed + ' failed ' + (failed === 1 ? 'attempt' : 'attempts') + ').'
+      : 'No PIN required.';
+  }
+
   function updateLockUI() {
     lockBtn.textContent = lobbyLocked ? 'Unlock lobby' : 'Lock lobby';
     lockStatusEl.textContent = lobbyLocked
@@ -234,7 +333,10 @@
       tdUser.textContent = p.username;
 
       const tdCeleb = document.createElement('td');
-      tdCeleb.textContent = p.celebrity;
+      tdCeleb.textContent = p.celebrity || (celebritiesHidden ? 'Hidden while you play' : '');
+
+      const tdTeam = document.createElement('td');
+      tdTeam.textContent = p.team || '';
 
       const tdActions = document.createElement('td');
       const btn = document.createElement('button');
@@ -244,8 +346,17 @@
       btn.textContent = 'Kick';
       tdActions.appendChild(btn);
 
+      const banBtn = document.createElement('button');
+      banBtn.type = 'button';
+      banBtn.className = 'kick-btn ban-btn';
+      banBtn.dataset.username = p.username;
+      banBtn.dataset.ban = 'true';
+      banBtn.textContent = 'Ban';
+      tdActions.appendChild(banBtn);
+
       tr.appendChild(tdUser);
       tr.appendChild(tdCeleb);
+      tr.appendChild(tdTeam);
       tr.appendChild(tdActions);
 
       playersBody.appendChild(tr);
@@ -254,6 +365,140 @@
     updatePlayerSummary(players);
   }
 
+  function renderPendingPlayers(pending) {
+    pendingBody.innerHTML = '';
+    pendingSection.style.display = pending.length ? 'block' : 'none';
+    pendingCountEl.textContent = `(${pending.length})`;
+
+    pending.forEach(function(p) {
+      const tr = document.createElement('tr');
+
+      const tdUser = document.createElement('td');
+      tdUser.textContent = p.username;
+
+      const tdCeleb = document.createElement('td');
+      tdCeleb.textContent = p.celebrity || (celebritiesHidden ? 'Hidden while you play' : '');
+
+      const tdActions = document.createElement('td');
+      [['approve_join', 'Approve', 'approve-btn'], ['reject_join', 'Reject', 'kick-btn']].forEach(function(a) {
+        const btn = document.createElement('button');
+        btn.type = 'button';
+        btn.className = a[2];
+        btn.dataset.action = a[0];
+        btn.dataset.username = p.username;
+        btn.textContent = a[1];
+        tdActions.appendChild(btn);
+      });
+
+      tr.appendChild(tdUser);
+      tr.appendChild(tdCeleb);
+      tr.appendChild(tdActions);
+
+      pendingBody.appendChild(tr);
+    });
+  }
+
+  const DEVICE_ROLES = {
+    host: 'Host',
+    co_moderator: 'Co-moderator',
+    spectator: 'Spectator',
+    viewer: 'Not joined'
+  };
+
+  function updateSpectatorSummary(count, displays) {
+    const parts = [];
+    if (count) {
+      parts.push(count + ' ' + (count === 1 ? 'spectator' : 'spectators') + ' watching.');
+    }
+    if (displays) {
+      parts.push(displays + ' ' + (displays === 1 ? 'display' : 'displays') + ' paired.');
+    }
+    spectatorSummaryEl.textContent = parts.join(' ');
+  }
+
+  function renderDevices(devices) {
+    devicesBody.innerHTML = '';
+    const others = devices.filter(function(d) {
+      return d.code !== deviceCode;
+    });
+    devicesSection.style.display = others.length ? 'block' : 'none';
+    deviceCountEl.textContent = `(${others.length})`;
+
+    others.forEach(function(d) {
+      const tr = document.createElement('tr');
+
+      const tdCode = document.createElement('td');
+      tdCode.textContent = d.code;
+
+      const tdRole = document.createElement('td');
+      tdRole.textContent = DEVICE_ROLES[d.role] || d.role;
+
+      const tdActions = document.createElement('td');
+      const actions = [];
+      if (d.role === 'spectator' && !gameStarted) {
+        actions.push(['promote_spectator', 'Let play', 'approve-btn']);
+      }
+      if (isHost && d.role !== 'host') {
+        actions.push(['transfer_moderator', 'Make host', 'approve-btn']);
+        if (d.role === 'co_moderator') {
+          actions.push(['remove_co_moderator', 'Remove co-moderator', 'kick-btn']);
+        } else {
+          actions.push(['add_co_moderator', 'Make co-moderator', 'approve-btn']);
+        }
+      }
+      actions.forEach(function(a) {
+        const btn = document.createElement('button');
+        btn.type = 'button';
+        btn.className = a[2];
+        btn.dataset.action = a[0];
+        btn.dataset.code = d.code;
+        btn.textContent = a[1];
+        tdActions.appendChild(btn);
+      });
+
+      tr.appendChild(tdCode);
+      tr.appendChild(tdRole);
+      tr.appendChild(tdActions);
+
+      devicesBody.appendChild(tr);
+    });
+  }
+
+  function showModeratorVote(msg) {
+    voteButtons.innerHTML = '';
+    const candidates = Array.isArray(msg.candidates) ? msg.candidates : [];
+
+    if (!username || isModerator) {
+      voteTextEl.textContent = 'The moderator has disconnected, so the players are choosing a new one.';
+    } else {
+      voteTextEl.textContent = 'The moderator has disconnected. Vote for who should take over before ' +
+        new Date(msg.ends_at).toLocaleTimeString() + '.';
+
+      candidates.forEach(function(name) {
+        const btn = document.createElement('button');
+        btn.type = 'button';
+        btn.className = 'approve-btn';
+        btn.dataset.username = name;
+        btn.textContent = name === username ? name + ' (you)' : name;
+        voteButtons.appendChild(btn);
+      });
+    }
+
+    votePanel.style.display = 'block';
+  }
+
+  voteButtons.addEventListener('click', function(e) {
+    const btn = e.target.closest('button');
+    if (!btn) return;
+
+    safeSend({
+      type: 'vote_moderator',
+      target_username: btn.dataset.username
+    });
+    voteTextEl.textContent = 'You voted for ' + btn.dataset.username + '. Waiting for the other players…';
+    voteButtons.innerHTML = '';
+  });
+
   function describeTeams(teams) {
     if (!Array.isArray(teams) || !teams.length) return '';
     const parts = teams.map(function(t) {
@@ -323,7 +568,7 @@
 
   function openGuessModal(celebrity) {
     if (!gameStarted) return;
-    if (!username || isModerator || amOut) return;
+    if (!username || amOut) return;
     if (currentTurnUser && currentTurnUser !== username) {
       statusEl.textContent = 'It is ' + currentTurnUser + '\'s turn.';
       return;
@@ -389,10 +634,40 @@
 
   qrBtn.addEventListener('click', function() {
     const base = location.pathname.replace(/\/$/, '');
+    qrTitle.textContent = 'Join this game';
+    qrText.textContent = 'Scan this QR code to open the current session on another device.';
+    qrLink.style.display = 'none';
     qrImage.src = base + '/qr';
     qrModal.style.display = 'flex';
   });
 
+  function showInvite(msg) {
+    const link = new URL(msg.path, location.origin);
+    const base = location.pathname.replace(/\/$/, '');
+
+    const notes = [];
+    if (msg.role === 'spectator') notes.push('as a spectator');
+    if (msg.team) notes.push('joins team ' + msg.team);
+    if (msg.single_use) notes.push('works once');
+    if (msg.expires_at) notes.push('expires ' + new Date(msg.expires_at).toLocaleString());
+
+    if (msg.kind === 'reclaim') {
+      qrTitle.textContent = 'Moderator reclaim link';
+      qrText.textContent = 'Keep this link private. Opening it on another device makes that device the moderator. ' +
+        'It works once, and creating a new one disables this one.';
+    } else {
+      qrTitle.textContent = 'Invite link';
+      qrText.textContent = 'Scan this QR code or share the link below to invite someone' +
+        (notes.length ? ' (' + notes.join(', ') + ')' : '') + '.';
+    }
+
+    qrLink.value = link.href;
+    qrLink.style.display = 'block';
+    qrImage.src = base + '/qr?' + link.searchParams.toString();
+    qrModal.style.display = 'flex';
+    qrLink.select();
+  }
+
   qrClose.addEventListener('click', function() {
     qrModal.style.display = 'none';
   });
@@ -403,6 +678,12 @@
     }
   });
 
+  spectateBtn.addEventListener('click', function() {
+    safeSend({
+      type: 'spectate'
+    });
+  });
+
   newGameBtn.addEventListener('click', function() {
     const parts = location.pathname.replace(/\/+$/, '').split('/');
     if (parts.length <= 1) {
@@ -414,25 +695,57 @@
   });
 
   function handleSessionInfo(msg) {
+    inviteToken = '';
     lobbyLocked = !!msg.lobby_locked;
     const isExisting = !!msg.is_existing;
+    const wasModerator = isModerator;
+    const firstSession = !sessionSeen;
+    sessionSeen = true;
     isModerator = !!msg.is_moderator;
+    isHost = !!msg.is_host;
+    isSpectator = !!msg.is_spectator;
+    const invited = !!msg.invited;
+    deviceCode = msg.code || '';
+    pinRequired = !!msg.pin_required;
     const existingName = msg.username || '';
 
-    if (lobbyLocked && !isExisting && !isModerator) {
+    // Session info is sent again whenever the moderators change, so only
+    // ask to join on connecting or after losing moderator rights.
+    const mayPrompt = firstSession || wasModerator;
+
+    reclaimBtn.style.display = isHost ? '' : 'none';
+    spectateBtn.style.display = (isModerator || isExisting || isSpectator) ? 'none' : 'inline-flex';
+
+    if (isSpectator) {
+      modPanel.style.display = 'none';
+      userNameEl.textContent = 'Spectator';
+      statusEl.textContent = 'You are watching as a spectator. Ask the moderator if you would like to play.';
+      return;
+    }
+
+    if (!isModerator) {
+      modPanel.style.display = 'none';
+      if (!existingName && deviceCode) {
+        userNameEl.textContent = 'Device ' + deviceCode;
+      }
+    }
+
+    if (lobbyLocked && !isExisting && !isModerator && !invited) {
       statusEl.textContent = 'Lobby is locked; no new players may join.';
       return;
     }
 
     if (isModerator) {
+      const role = isHost ? 'the moderator' : 'a co-moderator';
       if (existingName) {
+        username = existingName;
         userNameEl.textContent = existingName;
       } else {
-        userNameEl.textContent = 'Moderator';
+        userNameEl.textContent = isHost ? 'Moderator' : 'Co-moderator';
       }
       statusEl.textContent = lobbyLocked
-        ? 'You are the moderator. Lobby is locked.'
-        : 'You are the moderator. Lobby is unlocked.';
+        ? 'You are ' + role + '. Lobby is locked.'
+        : 'You are ' + role + '. Lobby is unlocked.';
       modPanel.style.display = 'block';
       updateLockUI();
       return;
@@ -449,11 +762,21 @@
       return;
     }
 
-    statusEl.textContent = 'Lobby is unlocked. Please join the game.';
-    username = prompt('Enter your username:') || '';
+    statusEl.textContent = 'Please join the game, or choose Watch to follow along without playing.';
+    if (mayPrompt) {
+      promptJoin();
+    }
+  }
+
+  function promptJoin() {
+    if (pinRequired && !pin) {
+      pin = prompt('Enter the PIN for this game:') || '';
+      if (!pin) return;
+    }
+    username = prompt('Enter your username:', username) || '';
     if (!username) return;
     userNameEl.textContent = username;
-    celeb = prompt('Enter a celebrity name:') || '';
+    celeb = prompt('Enter a celebrity name:', celeb) || '';
     if (!celeb) return;
     sendJoin();
   }
@@ -502,6 +825,53 @@
     });
   });
 
+  pinBtn.addEventListener('click', function() {
+    if (!isModerator) return;
+    const newPIN = prompt('Enter a PIN players must provide to join, or leave blank to remove it:');
+    if (newPIN === null) return;
+    safeSend({
+      type: 'set_pin',
+      pin: newPIN
+    });
+  });
+
+  pairDisplayBtn.addEventListener('click', function() {
+    if (!isModerator) return;
+    const code = prompt('Open ' + location.origin + location.pathname.replace(/\/$/, '') + '/display on the shared screen, then enter the code it shows:');
+    if (!code) return;
+    safeSend({
+      type: 'pair_display',
+      code: code.trim()
+    });
+  });
+
+  inviteBtn.addEventListener('click', function() {
+    if (!isModerator) return;
+    const spectator = confirm('Invite a spectator, who can watch but not play? Choose Cancel to invite a player.');
+    const team = spectator ? '' : prompt('Team for the invited player (optional):', '');
+    if (team === null) return;
+    const minutes = prompt('Expire the link after how many minutes? Leave blank for never:', '60');
+    if (minutes === null) return;
+    const singleUse = confirm('Allow the link to be used only once?');
+    safeSend({
+      type: 'create_invite',
+      role: spectator ? 'spectator' : 'player',
+      team: team.trim() || undefined,
+      single_use: singleUse,
+      duration: minutes.trim() ? minutes.trim() + 'm' : undefined
+    });
+  });
+
+  reclaimBtn.addEventListener('click', function() {
+    if (!isModerator) return;
+    if (!confirm('Create a private link to regain moderator rights from another device? Any earlier reclaim link will stop working.')) {
+      return;
+    }
+    safeSend({
+      type: 'create_reclaim_link'
+    });
+  });
+
   startBtn.addEventListener('click', function() {
     if (!isModerator) return;
     if (gameStarted) return;
@@ -526,6 +896,22 @@
     const targetUsername = btn.dataset.username;
     if (!targetUsername) return;
 
+    if (btn.dataset.ban) {
+      if (!confirm('Ban ' + targetUsername + ' from this game?')) {
+        return;
+      }
+
+      const banIP = confirm('Also ban their network address? This may block other people on the same network.');
+
+      safeSend({
+        type: 'kick',
+        target_username: targetUsername,
+        ban: true,
+        ban_ip: banIP
+      });
+      return;
+    }
+
     if (!confirm('Kick ' + targetUsername + '?')) {
       return;
     }
@@ -536,5 +922,32 @@
     });
   });
 
+  devicesBody.addEventListener('click', function(e) {
+    if (!isModerator) return;
+    const btn = e.target.closest('button[data-action]');
+    if (!btn) return;
+
+    if (btn.dataset.action === 'transfer_moderator' &&
+        !confirm('Make device ' + btn.dataset.code + ' the host? You will no longer be a moderator.')) {
+      return;
+    }
+
+    safeSend({
+      type: btn.dataset.action,
+      target: btn.dataset.code
+    });
+  });
+
+  pendingBody.addEventListener('click', function(e) {
+    if (!isModerator) return;
+    const btn = e.target.closest('button[data-action]');
+    if (!btn) return;
+
+    safeSend({
+      type: btn.dataset.action,
+      target_username: btn.dataset.username
+    });
+  });
+
   connectWebSocket();
 })();
@@ -0,0 +1,168 @@
+(function() {
+  const statusEl = document.getElementById('status');
+  const turnEl = document.getElementById('display-turn');
+  const pairingEl = document.getElementById('pairing');
+  const pairingCodeEl = document.getElementById('pairing-code');
+  const mainEl = document.getElementById('display-main');
+  const lobbyEl = document.getElementById('display-lobby');
+  const boardEl = document.getElementById('display-board');
+  const joinQR = document.getElementById('join-qr');
+  const joinLinkEl = document.getElementById('join-link');
+  const celebsEl = document.getElementById('display-celebs');
+  const teamsEl = document.getElementById('display-teams');
+  const feedEl = document.getElementById('display-feed');
+
+  const MAX_FEED = 8;
+
+  // The game's own page, which players open to join.
+  const gamePath = location.pathname.replace(/\/$/, '').replace(/\/display$/, '');
+
+  // A paired display keeps its token for the rest of the browser session, so
+  // that it does not need pairing again after a dropped connection.
+  const TOKEN_KEY = 'partybox-display:' + gamePath;
+
+  let ws = null;
+  let connectAttempts = 0;
+  const MAX_CONNECT_ATTEMPTS = 30;
+  let refused = false;
+  let gameStarted = false;
+  let celebrities = [];
+
+  joinQR.src = gamePath + '/qr';
+  joinLinkEl.textContent = location.origin + gamePath;
+
+  function wsURL() {
+    const proto = (location.protocol === 'https:') ? 'wss://' : 'ws://';
+    const token = sessionStorage.getItem(TOKEN_KEY) || '';
+    const query = token ? '?display=' + encodeURIComponent(token) : '';
+    return proto + location.host + gamePath + '/display/ws' + query;
+  }
+
+  function connectWebSocket() {
+    connectAttempts++;
+    statusEl.textContent = 'Connecting…';
+
+    ws = new WebSocket(wsURL());
+
+    ws.onopen = function() {
+      connectAttempts = 0;
+      statusEl.textContent = '';
+    };
+
+    ws.onmessage = function(event) {
+      try {
+        const msg = JSON.parse(event.data);
+
+        if (msg.type === 'display_pairing') {
+          sessionStorage.removeItem(TOKEN_KEY);
+          pairingCodeEl.textContent = msg.code;
+          pairingEl.style.display = 'block';
+          mainEl.style.display = 'none';
+          return;
+        }
+
+        if (msg.type === 'display_paired') {
+          sessionStorage.setItem(TOKEN_KEY, msg.token);
+          pairingEl.style.display = 'none';
+          mainEl.style.display = 'block';
+          return;
+        }
+
+        if (msg.type === 'celebrity_list' && Array.isArray(msg.celebrities)) {
+          celebrities = msg.celebrities;
+          renderCelebs();
+          return;
+        }
+
+        if (msg.type === 'game_state') {
+          renderGameState(msg);
+          return;
+        }
+
+        if (msg.type === 'guess_result') {
+          addToFeed(msg);
+          return;
+        }
+
+        if (msg.type === 'error') {
+          refused = true;
+          statusEl.textContent = msg.message || 'Unable to show this game.';
+          return;
+        }
+      } catch (e) {
+        console.error('bad message', e);
+      }
+    };
+
+    ws.onclose = function() {
+      if (refused) {
+        return;
+      }
+      if (connectAttempts >= MAX_CONNECT_ATTEMPTS) {
+        statusEl.textContent = 'Disconnected. Reload the page to try again.';
+        return;
+      }
+      statusEl.textContent = 'Disconnected. Reconnecting…';
+      setTimeout(connectWebSocket, Math.min(1000 * connectAttempts, 10000));
+    };
+  }
+
+  function renderCelebs() {
+    celebsEl.innerHTML = '';
+    celebrities.forEach(function(c) {
+      const li = document.createElement('li');
+      li.textContent = c;
+      celebsEl.appendChild(li);
+    });
+  }
+
+  function renderGameState(state) {
+    gameStarted = !!state.started;
+
+    const teams = Array.isArray(state.teams) ? state.teams : [];
+    // A lone player in the lobby is reported as the winner, so only a game
+    // in which someone was guessed counts as finished.
+    const finished = !gameStarted && !!state.winner &&
+      Array.isArray(state.eliminated) && state.eliminated.length > 0;
+    const showBoard = gameStarted || finished;
+    lobbyEl.style.display = showBoard ? 'none' : 'block';
+    boardEl.style.display = showBoard ? 'flex' : 'none';
+
+    if (gameStarted) {
+      turnEl.textContent = state.current_turn ? state.current_turn + '\'s turn' : '';
+    } else if (finished) {
+      turnEl.textContent = 'Winner: ' + state.winner;
+    } else {
+      turnEl.textContent = 'Waiting for the game to start';
+      feedEl.innerHTML = '';
+    }
+
+    teamsEl.innerHTML = '';
+    teams.forEach(function(t) {
+      const li = document.createElement('li');
+      const leader = document.createElement('strong');
+      leader.textContent = t.leader || '(unknown)';
+      li.appendChild(leader);
+      if (Array.isArray(t.members) && t.members.length) {
+        li.appendChild(document.createTextNode(' with ' + t.members.join(', ')));
+      }
+      const members = [t.leader].concat(t.members || []);
+      if (gameStarted && members.indexOf(state.current_turn) !== -1) {
+        li.className = 'current';
+      }
+      teamsEl.appendChild(li);
+    });
+  }
+
+  function addToFeed(result) {
+    const li = document.createElement('li');
+    li.className = result.correct ? 'correct' : 'incorrect';
+    li.textContent = result.message || '';
+    feedEl.insertBefore(li, feedEl.firstChild);
+    while (feedEl.children.length > MAX_FEED) {
+      feedEl.removeChild(feedEl.lastChild);
+    }
+  }
+
+  connectWebSocket();
+})();
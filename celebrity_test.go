@@ -0,0 +1,792 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"log/slog"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(discardLogger)
+
+	os.Exit(m.Run())
+}
+
+// newTestClient returns a client without a connection, whose messages are
+// buffered for inspection.
+func newTestClient(playerID string) *Client {
+	return &Client{
+		send:     make(chan outgoingMessage, 256),
+		commands: newTokenBucket(rateLimit{}),
+		admitted: make(chan bool, 1),
+		playerID: playerID,
+		log:      discardLogger,
+
+		connectedAt: time.Now(),
+	}
+}
+
+// connectTestClient adds a client to a hub which is not running, as if it
+// had connected at the given time.
+func connectTestClient(h *Hub, playerID string, connectedAt time.Time) *Client {
+	c := newTestClient(playerID)
+	c.connectedAt = connectedAt
+	h.clients[c] = true
+
+	return c
+}
+
+// startTestHub runs a hub for the duration of a test.
+func startTestHub(t *testing.T, cfg *Config) *Hub {
+	t.Helper()
+
+	h := newHub("celebrity", "TEST", "192.0.2.1")
+	go h.run(cfg)
+
+	return h
+}
+
+// registerTestClient connects a client to a running hub, and waits until
+// the hub has decided whether to admit it.
+func registerTestClient(t *testing.T, h *Hub, playerID string) *Client {
+	t.Helper()
+
+	c := newTestClient(playerID)
+	h.register <- c
+
+	if !<-c.admitted {
+		t.Fatalf("client %q was refused", playerID)
+	}
+
+	return c
+}
+
+// syncHub waits until a running hub has handled everything sent to it so
+// far, since it handles one request at a time.
+func syncHub(h *Hub) {
+	h.votes <- voteRequest{client: newTestClient("")}
+}
+
+// joinTestPlayer joins a client to a running hub as a player.
+func joinTestPlayer(t *testing.T, h *Hub, c *Client, username, celebrity string) {
+	t.Helper()
+
+	h.joins <- joinRequest{
+		client: c,
+		msg:    ClientMessage{Type: "join", Username: username, Celebrity: celebrity},
+	}
+	syncHub(h)
+
+	if _, ok := lastOfType(c, "joined"); !ok {
+		t.Fatalf("%s was not able to join", username)
+	}
+}
+
+// sendModCommand sends a moderator command to a running hub, and waits for
+// it to be handled.
+func sendModCommand(h *Hub, c *Client, msg ClientMessage) {
+	h.mods <- modCommand{client: c, msg: msg}
+	syncHub(h)
+}
+
+// received drains the messages sent to a client so far.
+func received(c *Client) []outgoingMessage {
+	return drain(c.send)
+}
+
+// drain returns the messages buffered in a send channel.
+func drain(send chan outgoingMessage) []outgoingMessage {
+	var msgs []outgoingMessage
+
+	for {
+		select {
+		case msg, ok := <-send:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// lastOfType returns the last message of a type sent to a client so far.
+func lastOfType(c *Client, typ string) (outgoingMessage, bool) {
+	var last outgoingMessage
+
+	for _, msg := range received(c) {
+		if msg.MessageType() == typ {
+			last = msg
+		}
+	}
+
+	return last, last != nil
+}
+
+func TestFailover(t *testing.T) {
+	start := time.Now()
+
+	type device struct {
+		id   string
+		role string // "player", "spectator", "co_moderator", or "viewer"
+		age  time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		devices []device
+		want    string // new host, or empty if failover stays pending
+	}{
+		{"co-moderator first", failoverLongestConnected, []device{
+			{"p1", "player", 3 * time.Minute},
+			{"s1", "spectator", 2 * time.Minute},
+			{"c1", "co_moderator", time.Minute},
+		}, "c1"},
+		{"co-moderator only", failoverCoModerator, []device{
+			{"p1", "player", 3 * time.Minute},
+			{"s1", "spectator", 2 * time.Minute},
+		}, ""},
+		{"spectator before players", failoverLongestConnected, []device{
+			{"p1", "player", 3 * time.Minute},
+			{"s1", "spectator", time.Minute},
+		}, "s1"},
+		{"only players", failoverLongestConnected, []device{
+			{"p1", "player", time.Minute},
+			{"p2", "player", 3 * time.Minute},
+			{"p3", "player", 2 * time.Minute},
+		}, "p2"},
+		{"never a viewer", failoverLongestConnected, []device{
+			{"v1", "viewer", 3 * time.Minute},
+			{"p1", "player", time.Minute},
+		}, "p1"},
+		{"only viewers", failoverLongestConnected, []device{
+			{"v1", "viewer", 3 * time.Minute},
+		}, ""},
+		{"nobody", failoverLongestConnected, nil, ""},
+		{"vote with one player", failoverVote, []device{
+			{"v1", "viewer", 3 * time.Minute},
+			{"p1", "player", time.Minute},
+		}, "p1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{moderatorFailover: tt.mode}
+
+			h := newHub("celebrity", "TEST", "")
+			h.moderatorPlayerID = "host"
+
+			for _, d := range tt.devices {
+				connectTestClient(h, d.id, start.Add(-d.age))
+
+				switch d.role {
+				case "player":
+					h.players = append(h.players, Player{PlayerID: d.id, Username: "name-" + d.id, Celebrity: "celebrity-" + d.id})
+				case "spectator":
+					h.spectators[d.id] = true
+				case "co_moderator":
+					h.coModerators[d.id] = true
+				}
+			}
+
+			h.failoverLocked(cfg)
+
+			if tt.want == "" {
+				if !h.failoverPending || h.moderatorPlayerID != "host" {
+					t.Errorf("host = %q, pending %v, want failover pending", h.moderatorPlayerID, h.failoverPending)
+				}
+				return
+			}
+
+			if h.moderatorPlayerID != tt.want || h.failoverPending || h.awayHost != "host" {
+				t.Errorf("host = %q, pending %v, away %q, want %q", h.moderatorPlayerID, h.failoverPending, h.awayHost, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailoverHidesCelebrities(t *testing.T) {
+	h := newHub("celebrity", "TEST", "")
+	h.moderatorPlayerID = "host"
+
+	alice := connectTestClient(h, "a", time.Now().Add(-time.Minute))
+	connectTestClient(h, "b", time.Now())
+	h.players = []Player{
+		{PlayerID: "a", Username: "Alice", Celebrity: "Cher"},
+		{PlayerID: "b", Username: "Bob", Celebrity: "Prince"},
+	}
+
+	h.failoverLocked(&Config{moderatorFailover: failoverLongestConnected})
+
+	if h.moderatorPlayerID != "a" {
+		t.Fatalf("host = %q, want a", h.moderatorPlayerID)
+	}
+
+	msg, ok := lastOfType(alice, "moderator_view")
+	if !ok {
+		t.Fatal("new host was not sent the moderator view")
+	}
+
+	view := msg.(ModeratorViewMessage)
+	if !view.CelebritiesHidden {
+		t.Error("celebrities not marked hidden from a playing host")
+	}
+	for _, p := range view.Players {
+		if p.Username == "Alice" && p.Celebrity != "Cher" {
+			t.Errorf("host's own celebrity hidden: %+v", p)
+		}
+		if p.Username != "Alice" && p.Celebrity != "" {
+			t.Errorf("celebrity of %s shown to a playing host", p.Username)
+		}
+	}
+}
+
+func TestFailoverVote(t *testing.T) {
+	h := newHub("celebrity", "TEST", "")
+	h.moderatorPlayerID = "host"
+
+	start := time.Now()
+	voters := map[string]*Client{}
+	for i, id := range []string{"a", "b", "c"} {
+		voters[id] = connectTestClient(h, id, start.Add(time.Duration(i)*time.Second))
+		h.players = append(h.players, Player{PlayerID: id, Username: "name-" + id})
+	}
+	connectTestClient(h, "viewer", start.Add(-time.Hour))
+
+	h.failoverLocked(&Config{moderatorFailover: failoverVote})
+
+	if h.vote == nil {
+		t.Fatal("no vote started")
+	}
+	msg, ok := lastOfType(voters["a"], "moderator_vote")
+	if !ok {
+		t.Fatal("players were not asked to vote")
+	}
+	if got := msg.(ModeratorVoteMessage).Candidates; len(got) != 3 || got[0] != "name-a" {
+		t.Errorf("candidates = %q, want the three players, longest connected first", got)
+	}
+
+	vote := func(voter, candidate string) {
+		h.handleVote(voteRequest{
+			client: voters[voter],
+			msg:    ClientMessage{Type: "vote_moderator", TargetUsername: candidate},
+		})
+	}
+
+	vote("a", "name-c")
+	vote("b", "name-c")
+	if h.vote == nil {
+		t.Fatal("vote finished before everyone voted")
+	}
+
+	vote("c", "name-b")
+	if h.vote != nil || h.moderatorPlayerID != "c" {
+		t.Errorf("host = %q after the vote, want c", h.moderatorPlayerID)
+	}
+}
+
+func TestFailoverRetry(t *testing.T) {
+	cfg := &Config{moderatorFailover: failoverLongestConnected}
+	h := startTestHub(t, cfg)
+
+	h.mu.Lock()
+	h.moderatorPlayerID = "host"
+	h.players = []Player{{PlayerID: "p1", Username: "Alice", Celebrity: "Cher"}}
+	h.failoverPending = true
+	h.mu.Unlock()
+
+	// Somebody who has only opened the link does not take over.
+	registerTestClient(t, h, "stranger")
+	syncHub(h)
+	time.Sleep(10 * time.Millisecond)
+
+	h.mu.RLock()
+	host := h.moderatorPlayerID
+	h.mu.RUnlock()
+	if host != "host" {
+		t.Fatalf("host = %q after a stranger connected", host)
+	}
+
+	// A returning player does.
+	registerTestClient(t, h, "p1")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mu.RLock()
+		host, pending := h.moderatorPlayerID, h.failoverPending
+		h.mu.RUnlock()
+
+		if host == "p1" && !pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("host = %q, pending %v after a player reconnected", host, pending)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHostReturns(t *testing.T) {
+	cfg := &Config{moderatorFailover: failoverLongestConnected}
+	h := startTestHub(t, cfg)
+
+	h.mu.Lock()
+	h.moderatorPlayerID = "host"
+	h.players = []Player{{PlayerID: "p1", Username: "Alice", Celebrity: "Cher"}}
+	connectTestClient(h, "p1", time.Now())
+	h.failoverLocked(cfg)
+	h.mu.Unlock()
+
+	registerTestClient(t, h, "host")
+	syncHub(h)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.moderatorPlayerID != "host" || h.awayHost != "" || h.isModeratorLocked("p1") {
+		t.Errorf("host = %q, away %q, want the host back and the player demoted", h.moderatorPlayerID, h.awayHost)
+	}
+}
+
+// TestClientCommandsCoverApp checks that every command the web client sends
+// is routed to the hub, and so also counted in metrics.
+func TestClientCommandsCoverApp(t *testing.T) {
+	js, err := os.ReadFile("celebrity/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := regexp.MustCompile(`type: '([a-z_]+)'`).FindAllSubmatch(js, -1)
+	if len(sent) == 0 {
+		t.Fatal("found no commands in app.js")
+	}
+
+	for _, m := range sent {
+		if _, ok := clientCommands[string(m[1])]; !ok {
+			t.Errorf("command %q sent by app.js is not routed", m[1])
+		}
+	}
+
+	// Actions sent from data attributes rather than literals.
+	for _, typ := range []string{"approve_join", "reject_join", "transfer_moderator", "add_co_moderator", "remove_co_moderator", "promote_spectator"} {
+		if _, ok := clientCommands[typ]; !ok {
+			t.Errorf("command %q is not routed", typ)
+		}
+	}
+}
+
+// newTestDisplay returns a display without a connection, whose messages are
+// buffered for inspection.
+func newTestDisplay(token string) *Display {
+	return &Display{
+		send:  make(chan outgoingMessage, 16),
+		token: token,
+		log:   discardLogger,
+
+		connectedAt: time.Now(),
+	}
+}
+
+// pairingCode returns the code shown on a display waiting to be paired.
+func pairingCode(t *testing.T, d *Display) string {
+	t.Helper()
+
+	for _, msg := range drain(d.send) {
+		if m, ok := msg.(DisplayPairingMessage); ok {
+			return m.Code
+		}
+	}
+
+	t.Fatal("display was not sent a pairing code")
+	return ""
+}
+
+func TestDisplayPairing(t *testing.T) {
+	cfg := &Config{cookieSecrets: []string{string(testCookieKey)}}
+	h := startTestHub(t, cfg)
+	host := registerTestClient(t, h, "host")
+	player := registerTestClient(t, h, "player")
+
+	d := newTestDisplay("")
+	if err := h.addDisplay(cfg, d); err != nil {
+		t.Fatal(err)
+	}
+	code := pairingCode(t, d)
+
+	// Only a moderator can pair a display.
+	sendModCommand(h, player, ClientMessage{Type: "pair_display", Code: code})
+	if d.paired {
+		t.Fatal("a player paired the display")
+	}
+
+	sendModCommand(h, host, ClientMessage{Type: "pair_display", Code: "WRONG1"})
+	if _, ok := lastOfType(host, "moderator_error"); !ok || d.paired {
+		t.Fatal("a display was paired with the wrong code")
+	}
+
+	sendModCommand(h, host, ClientMessage{Type: "pair_display", Code: strings.ToLower(code)})
+
+	var token string
+	for _, msg := range drain(d.send) {
+		if m, ok := msg.(DisplayPairedMessage); ok {
+			token = m.Token
+		}
+	}
+	if token == "" {
+		t.Fatal("display was not paired")
+	}
+
+	inv, err := decodeInvite(cfg.cookieKeys(), token, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Until(inv.expiresAt()); lifetime <= 0 || lifetime > displayTokenLifetime {
+		t.Errorf("token expires in %v, want within %v", lifetime, displayTokenLifetime)
+	}
+
+	// The display reconnects with its token, without a new code.
+	h.removeDisplay(d)
+	again := newTestDisplay(token)
+	if err := h.addDisplay(cfg, again); err != nil || !again.paired {
+		t.Errorf("display with token was not paired again: %v", err)
+	}
+
+	// Once the token has expired, a new code is shown instead.
+	expired, err := encodeInvite(testCookieKey, &invite{
+		Kind:    inviteDisplay,
+		Game:    h.game + "/" + h.id,
+		Created: h.createdAt.UnixNano(),
+		ID:      "old",
+		Expires: time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := newTestDisplay(expired)
+	if err := h.addDisplay(cfg, stale); err != nil || stale.paired {
+		t.Errorf("display with an expired token was paired: %v", err)
+	}
+	pairingCode(t, stale)
+}
+
+func TestDisplayLimits(t *testing.T) {
+	cfg := &Config{cookieSecrets: []string{string(testCookieKey)}}
+	h := startTestHub(t, cfg)
+	host := registerTestClient(t, h, "host")
+
+	waiting := make([]*Display, maxUnpairedDisplays)
+	codes := make([]string, maxUnpairedDisplays)
+	for i := range waiting {
+		waiting[i] = newTestDisplay("")
+		if err := h.addDisplay(cfg, waiting[i]); err != nil {
+			t.Fatal(err)
+		}
+		codes[i] = pairingCode(t, waiting[i])
+	}
+
+	// Further displays are refused, leaving the codes already shown intact.
+	if err := h.addDisplay(cfg, newTestDisplay("")); err != errTooManyUnpaired {
+		t.Errorf("display beyond the unpaired limit: err = %v, want %v", err, errTooManyUnpaired)
+	}
+	for i, d := range waiting {
+		if msgs := drain(d.send); len(msgs) != 0 {
+			t.Errorf("waiting display %d was sent %v", i, msgs)
+		}
+	}
+
+	// Pairing one makes room for another.
+	sendModCommand(h, host, ClientMessage{Type: "pair_display", Code: codes[0]})
+	if !waiting[0].paired {
+		t.Fatal("display was not paired")
+	}
+	if err := h.addDisplay(cfg, newTestDisplay("")); err != nil {
+		t.Errorf("display after pairing: %v", err)
+	}
+
+	// Paired displays are bounded separately.
+	h.mu.Lock()
+	for i := 1; i < maxDisplays; i++ {
+		paired := newTestDisplay("")
+		paired.paired = true
+		h.displays[paired] = true
+	}
+	h.mu.Unlock()
+
+	sendModCommand(h, host, ClientMessage{Type: "pair_display", Code: codes[1]})
+	if waiting[1].paired {
+		t.Error("display paired beyond the limit")
+	}
+	if msg, ok := lastOfType(host, "moderator_error"); !ok || msg.(SimpleMessage).Message != errTooManyDisplays.Error() {
+		t.Errorf("moderator was sent %v, want %q", msg, errTooManyDisplays)
+	}
+}
+
+func TestJoinWithPIN(t *testing.T) {
+	cfg := &Config{}
+	h := startTestHub(t, cfg)
+	h.pinLimiter = newRateLimiter("pin", func() rateLimit { return rateLimit{n: 2, interval: time.Hour} })
+
+	host := registerTestClient(t, h, "host")
+	joinTestPlayer(t, h, host, "Host", "Cher")
+	sendModCommand(h, host, ClientMessage{Type: "set_pin", PIN: "1234"})
+
+	alice := registerTestClient(t, h, "alice")
+	// join returns the types of the messages sent in reply to a join.
+	join := func(c *Client, username, pin string) []string {
+		received(c)
+		h.joins <- joinRequest{
+			client: c,
+			msg:    ClientMessage{Type: "join", Username: username, Celebrity: "Madonna " + username, PIN: pin},
+		}
+		syncHub(h)
+
+		var types []string
+		for _, msg := range received(c) {
+			types = append(types, msg.MessageType())
+		}
+		return types
+	}
+
+	steps := []struct {
+		name     string
+		client   *Client
+		username string
+		pin      string
+		want     string
+	}{
+		{"no pin", alice, "Alice", "", "pin_required"},
+		{"wrong pin", alice, "Alice", "0000", "pin_required"},
+		// A join refused for another reason does not use up an attempt.
+		{"taken name", alice, "Host", "1234", "collision"},
+		{"taken name again", alice, "Host", "1234", "collision"},
+		{"correct pin", alice, "Alice", "1234", "joined"},
+		// Players who have joined are not asked again.
+		{"rename", alice, "Alicia", "", "joined"},
+		{"attempts used up", registerTestClient(t, h, "bob"), "Bob", "1234", "rate_limited"},
+	}
+
+	for _, step := range steps {
+		got := join(step.client, step.username, step.pin)
+		if !slices.Contains(got, step.want) || (step.want != "joined" && slices.Contains(got, "joined")) {
+			t.Errorf("%s: got %q, want %q", step.name, got, step.want)
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.failedPINs != 1 {
+		t.Errorf("failed PIN attempts = %d, want 1", h.failedPINs)
+	}
+}
+
+func TestKick(t *testing.T) {
+	addr := netip.MustParseAddr("198.51.100.7")
+
+	tests := []struct {
+		name       string
+		ban, banIP bool
+		cookie, ip error // ban checks afterwards, for the kicked cookie and another on its address
+	}{
+		{"kick only", false, false, nil, nil},
+		{"ban cookie", true, false, errBannedFromGame, nil},
+		{"ban cookie and address", true, true, errBannedFromGame, errBannedFromGame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := bans
+			bans = &BanList{}
+			t.Cleanup(func() { bans = saved })
+
+			h := startTestHub(t, &Config{})
+			host := registerTestClient(t, h, "host")
+			joinTestPlayer(t, h, host, "Host", "Cher")
+
+			alice := newTestClient("alice")
+			alice.addr = addr
+			h.register <- alice
+			<-alice.admitted
+			joinTestPlayer(t, h, alice, "Alice", "Madonna")
+
+			kick := ClientMessage{Type: "kick", TargetUsername: "Alice", Ban: tt.ban, BanIP: tt.banIP}
+
+			// Only a moderator can kick.
+			sendModCommand(h, alice, kick)
+			if _, ok := lastOfType(alice, "kicked"); ok {
+				t.Fatal("a player kicked another")
+			}
+
+			sendModCommand(h, host, kick)
+			if _, ok := lastOfType(alice, "kicked"); !ok {
+				t.Error("kicked player was not told")
+			}
+			if _, open := <-alice.send; open {
+				t.Error("kicked player is still connected")
+			}
+
+			h.mu.RLock()
+			joined := h.isPlayerLocked("alice")
+			h.mu.RUnlock()
+			if joined {
+				t.Error("kicked player is still in the game")
+			}
+
+			game := h.game + "/" + h.id
+			if err := bans.check(game, "alice", netip.Addr{}); err != tt.cookie {
+				t.Errorf("kicked cookie: err = %v, want %v", err, tt.cookie)
+			}
+			if err := bans.check(game, "other", addr); err != tt.ip {
+				t.Errorf("kicked address: err = %v, want %v", err, tt.ip)
+			}
+			if err := bans.check("celebrity/OTHER", "alice", addr); err != nil {
+				t.Errorf("other game: err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestChangeModerators(t *testing.T) {
+	h := startTestHub(t, &Config{})
+	host := registerTestClient(t, h, "host")
+	tv := registerTestClient(t, h, "tv")
+	player := registerTestClient(t, h, "player")
+	joinTestPlayer(t, h, player, "Alice", "Cher")
+
+	// lockedBy reports whether a client may lock the lobby, leaving it
+	// unlocked again afterwards.
+	lockedBy := func(c *Client) bool {
+		locked, unlocked := true, false
+		sendModCommand(h, c, ClientMessage{Type: "lock_lobby", Lock: &locked})
+
+		h.mu.RLock()
+		ok := h.lobbyLocked
+		h.mu.RUnlock()
+
+		sendModCommand(h, host, ClientMessage{Type: "lock_lobby", Lock: &unlocked})
+
+		return ok
+	}
+
+	steps := []struct {
+		name   string
+		client *Client
+		action string
+		target string
+		err    string // moderator error expected, if any
+	}{
+		{"player", host, "add_co_moderator", "player", "Players cannot be made moderators."},
+		{"host", host, "add_co_moderator", "host", "That device is already the host."},
+		{"add", host, "add_co_moderator", "tv", ""},
+		{"add again", host, "add_co_moderator", "tv", "That device is already a co-moderator."},
+		{"by co-moderator", tv, "remove_co_moderator", "tv", "Only the host can change moderators."},
+		{"remove", host, "remove_co_moderator", "tv", ""},
+		{"remove again", host, "remove_co_moderator", "tv", "That device is not a co-moderator."},
+		{"disconnected", host, "add_co_moderator", "gone", "That device is no longer connected."},
+	}
+
+	for _, step := range steps {
+		received(step.client)
+		sendModCommand(h, step.client, ClientMessage{Type: step.action, Target: h.deviceCode(step.target)})
+
+		msg, ok := lastOfType(step.client, "moderator_error")
+		switch {
+		case step.err == "" && ok:
+			t.Errorf("%s: got error %q", step.name, msg.(SimpleMessage).Message)
+		case step.err != "" && (!ok || msg.(SimpleMessage).Message != step.err):
+			t.Errorf("%s: got %v, want error %q", step.name, msg, step.err)
+		}
+
+		if step.name == "add" && !lockedBy(tv) {
+			t.Error("co-moderator cannot moderate")
+		}
+		if step.name == "remove" && lockedBy(tv) {
+			t.Error("removed co-moderator can still moderate")
+		}
+	}
+
+	sendModCommand(h, host, ClientMessage{Type: "transfer_moderator", Target: h.deviceCode("tv")})
+
+	h.mu.RLock()
+	newHost := h.moderatorPlayerID
+	h.mu.RUnlock()
+	if newHost != "tv" {
+		t.Fatalf("host = %q after transfer, want tv", newHost)
+	}
+	if lockedBy(host) {
+		t.Error("former host can still moderate")
+	}
+}
+
+func TestSpectators(t *testing.T) {
+	h := startTestHub(t, &Config{})
+	host := registerTestClient(t, h, "host")
+	joinTestPlayer(t, h, host, "Host", "Cher")
+
+	spectate := func(c *Client) bool {
+		h.joins <- joinRequest{client: c, msg: ClientMessage{Type: "spectate"}}
+		syncHub(h)
+
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+
+		return h.isSpectatorLocked(c.playerID)
+	}
+
+	if spectate(host) {
+		t.Error("a player became a spectator")
+	}
+
+	watcher := registerTestClient(t, h, "watcher")
+	if !spectate(watcher) {
+		t.Fatal("client did not become a spectator")
+	}
+
+	h.joins <- joinRequest{client: watcher, msg: ClientMessage{Type: "join", Username: "Watcher", Celebrity: "Prince"}}
+	syncHub(h)
+	if _, ok := lastOfType(watcher, "spectating"); !ok {
+		t.Error("spectator was not told they cannot join")
+	}
+
+	// A promoted spectator may join a locked lobby without the PIN.
+	locked := true
+	sendModCommand(h, host, ClientMessage{Type: "lock_lobby", Lock: &locked})
+	sendModCommand(h, host, ClientMessage{Type: "set_pin", PIN: "1234"})
+	sendModCommand(h, host, ClientMessage{Type: "promote_spectator", Target: h.deviceCode("watcher")})
+
+	joinTestPlayer(t, h, watcher, "Watcher", "Prince")
+
+	// Once the game has started, spectators can no longer be promoted.
+	late := registerTestClient(t, h, "late")
+	spectate(late)
+
+	h.mu.Lock()
+	h.gameStarted = true
+	h.mu.Unlock()
+
+	received(host)
+	sendModCommand(h, host, ClientMessage{Type: "promote_spectator", Target: h.deviceCode("late")})
+	if _, ok := lastOfType(host, "moderator_error"); !ok {
+		t.Error("spectator was promoted after the game started")
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.isSpectatorLocked("late") {
+		t.Error("spectator is no longer spectating")
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/julienschmidt/httprouter"
+)
+
+// certReloadDelay debounces bursts of file events, such as a tool writing
+// the certificate and key separately.
+const certReloadDelay = 500 * time.Millisecond
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// certReloader serves the current key pair, replacing it whenever the files
+// change on disk or the process receives SIGHUP.
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	cr := &certReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
+func (cr *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certPath, cr.keyPath)
+	if err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.mu.Unlock()
+
+	return nil
+}
+
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	return cr.cert, nil
+}
+
+// watch reloads the key pair on change until ctx is done. The containing
+// directories are watched rather than the files, so that atomic renames and
+// symlink swaps (as used by Kubernetes secrets) are detected.
+func (cr *certReloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Unable to watch tls certificate files, reloading on SIGHUP only", "error", err)
+	} else {
+		for _, dir := range []string{filepath.Dir(cr.certPath), filepath.Dir(cr.keyPath)} {
+			if err := watcher.Add(dir); err != nil {
+				slog.Warn("Unable to watch tls certificate directory", "path", dir, "error", err)
+			}
+		}
+	}
+
+	var events <-chan fsnotify.Event
+	if watcher != nil {
+		events = watcher.Events
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		var pending <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				cr.reloadAndLog("signal")
+			case e, ok := <-events:
+				if !ok {
+					events = nil
+
+					continue
+				}
+				if cr.relevant(e.Name) {
+					pending = time.After(certReloadDelay)
+				}
+			case <-pending:
+				pending = nil
+				cr.reloadAndLog("file change")
+			}
+		}
+	}()
+}
+
+func (cr *certReloader) relevant(name string) bool {
+	name = filepath.Clean(name)
+
+	for _, p := range []string{cr.certPath, cr.keyPath} {
+		if name == filepath.Clean(p) {
+			return true
+		}
+	}
+
+	// Kubernetes swaps a ..data symlink when mounted secrets change.
+	return filepath.Base(name) == "..data"
+}
+
+func (cr *certReloader) reloadAndLog(trigger string) {
+	if err := cr.reload(); err != nil {
+		slog.Error("Failed to reload tls certificate, keeping previous", "trigger", trigger, "error", err)
+
+		return
+	}
+
+	cr.mu.RLock()
+	leaf := cr.cert.Leaf
+	cr.mu.RUnlock()
+
+	attrs := []any{"trigger", trigger}
+	if leaf != nil {
+		attrs = append(attrs, "not_after", leaf.NotAfter)
+	}
+
+	slog.Info("Reloaded tls certificate", attrs...)
+}
+
+// newTLSConfig builds the server tls.Config, including client certificate
+// verification when --tls-client-ca is set. Client certificates are
+// requested but not required at the handshake, so that only admin endpoints
+// enforce them; see requireClientCert.
+func newTLSConfig(ctx context.Context, cfg *Config) (*tls.Config, error) {
+	cr, err := newCertReloader(cfg.tlsCert, cfg.tlsKey)
+	if err != nil {
+		return nil, err
+	}
+	cr.watch(ctx)
+
+	tlsCfg := &tls.Config{
+		GetCertificate: cr.GetCertificate,
+		MinVersion:     tlsVersions[cfg.tlsMinVersion],
+	}
+
+	if cfg.tlsClientCA != "" {
+		pem, err := os.ReadFile(cfg.tlsClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tls client ca: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in tls client ca file")
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsCfg, nil
+}
+
+// requireClientCert restricts a handler to clients which presented a
+// certificate signed by --tls-client-ca. It has no effect if no client CA
+// is configured.
+func requireClientCert(cfg *Config, next http.Handler) http.Handler {
+	if cfg.tlsClientCA == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			slog.Warn("Rejected admin request without client certificate", "ip", realIP(r), "path", r.URL.Path)
+
+			securityHeaders(cfg, w)
+			http.Error(w, "client certificate required", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requireClientCertHandle(cfg *Config, next httprouter.Handle) httprouter.Handle {
+	if cfg.tlsClientCA == "" {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		requireClientCert(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next(w, r, p)
+		})).ServeHTTP(w, r)
+	}
+}
@@ -5,9 +5,15 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -16,24 +22,289 @@ import (
 )
 
 type Config struct {
-	bind           string
-	playerTimeout  time.Duration
-	port           int
-	prefix         string
-	profile        bool
-	sessionTimeout time.Duration
-	tlsCert        string
-	tlsKey         string
-	verbose        bool
-	version        bool
-
-	// baseURL *url.URL
+	accessLog               string
+	accessLogFormat         string
+	accessLogMaxAge         time.Duration
+	accessLogMaxBackups     int
+	accessLogMaxSize        int64
+	adminToken              string
+	allowedOrigins          []string
+	bind                    string
+	configFile              string
+	contentFilter           string
+	contentFilterBuiltin    bool
+	contentFilterWordlists  []string
+	cookieMaxAge            time.Duration
+	drainTimeout            time.Duration
+	gameBanDuration         time.Duration
+	logFormat               string
+	logLevel                string
+	maxCelebrityLength      int
+	maxConnections          int
+	maxConnectionsPerPlayer int
+	maxGames                int
+	maxGamesPerIP           int
+	maxPlayers              int
+	maxUsernameLength       int
+	maintenance             bool
+	metrics                 bool
+	metricsAddr             string
+	moderatorFailover       string
+	moderatorGracePeriod    time.Duration
+	opsAddr                 string
+	playerTimeout           time.Duration
+	port                    int
+	prefix                  string
+	profile                 bool
+	publicURL               string
+	rateLimitCommands       string
+	rateLimitGames          string
+	rateLimitHTTP           string
+	rateLimitPIN            string
+	redirectAddr            string
+	sessionTimeout          time.Duration
+	socketMode              string
+	store                   string
+	tlsCert                 string
+	tlsClientCA             string
+	tlsKey                  string
+	tlsMinVersion           string
+	traceEndpoint           string
+	traceSampleRatio        float64
+	traceServiceName        string
+	trustedProxies          []string
+	verbose                 bool
+	version                 bool
+
+	baseURL *url.URL
+
+	proxies []netip.Prefix
+
+	// Options which may change at runtime are guarded by mu; see
+	// reloadableFlags.
+	mu                sync.RWMutex
+	cookieSecrets     []string
+	cookieFallbackKey []byte
+	games             map[string]GameSettings
+	configLoader      *configFile
+	filter            *contentFilter
+	commandRateLimit  rateLimit
+	gameRateLimit     rateLimit
+	httpRateLimit     rateLimit
+	pinRateLimit      rateLimit
+}
+
+// Limits holds the resource and rate limits in effect.
+type Limits struct {
+	MaxCelebrityLength      int
+	MaxConnections          int
+	MaxConnectionsPerPlayer int
+	MaxGames                int
+	MaxGamesPerIP           int
+	MaxPlayers              int
+	MaxUsernameLength       int
+
+	Commands rateLimit
+	Games    rateLimit
+	HTTP     rateLimit
+	PIN      rateLimit
+}
+
+// limits returns the limits in effect, which may change when the config
+// file is reloaded.
+func (c *Config) limits() Limits {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return Limits{
+		MaxCelebrityLength:      c.maxCelebrityLength,
+		MaxConnections:          c.maxConnections,
+		MaxConnectionsPerPlayer: c.maxConnectionsPerPlayer,
+		MaxGames:                c.maxGames,
+		MaxGamesPerIP:           c.maxGamesPerIP,
+		MaxPlayers:              c.maxPlayers,
+		MaxUsernameLength:       c.maxUsernameLength,
+
+		Commands: c.commandRateLimit,
+		Games:    c.gameRateLimit,
+		HTTP:     c.httpRateLimit,
+		PIN:      c.pinRateLimit,
+	}
+}
+
+// contentFilterInUse returns the content filter in effect, which is nil if
+// filtering is off.
+func (c *Config) contentFilterInUse() *contentFilter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filter
+}
+
+// gameSettings returns the effective settings for a game type, with any
+// per-game overrides from the config file applied.
+func (c *Config) gameSettings(game string) GameSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s := GameSettings{
+		PlayerTimeout:  c.playerTimeout,
+		SessionTimeout: c.sessionTimeout,
+	}
+
+	if g, ok := c.games[game]; ok {
+		if g.PlayerTimeout > 0 {
+			s.PlayerTimeout = g.PlayerTimeout
+		}
+		if g.SessionTimeout > 0 {
+			s.SessionTimeout = g.SessionTimeout
+		}
+	}
+
+	return s
+}
+
+// validateReloadable checks the options which may be changed at runtime.
+func (c *Config) validateReloadable() error {
+	if _, err := parseLogLevel(c.logLevel); err != nil {
+		return fmt.Errorf("invalid log level (must be debug, info, warn, or error): %q", c.logLevel)
+	}
+	if c.playerTimeout < 0 {
+		return fmt.Errorf("invalid player timeout (must not be negative): %s", c.playerTimeout)
+	}
+	if c.sessionTimeout < 0 {
+		return fmt.Errorf("invalid session timeout (must not be negative): %s", c.sessionTimeout)
+	}
+	for _, secret := range c.cookieSecrets {
+		if len(secret) < minCookieSecretLength {
+			return fmt.Errorf("invalid cookie secret (must be at least %d characters)", minCookieSecretLength)
+		}
+	}
+	switch c.contentFilter {
+	case filterOff, filterReject, filterMask, filterApprove:
+	default:
+		return fmt.Errorf("invalid content filter action (must be off, reject, mask, or approve): %q", c.contentFilter)
+	}
+	for _, limit := range []int{c.maxCelebrityLength, c.maxConnections, c.maxConnectionsPerPlayer, c.maxGames, c.maxGamesPerIP, c.maxPlayers, c.maxUsernameLength} {
+		if limit < 0 {
+			return errors.New("resource limits must not be negative")
+		}
+	}
+	var rateLimits [4]rateLimit
+	for i, flag := range []string{c.rateLimitCommands, c.rateLimitGames, c.rateLimitHTTP, c.rateLimitPIN} {
+		limit, err := parseRateLimit(flag)
+		if err != nil {
+			return err
+		}
+		rateLimits[i] = limit
+	}
+	c.commandRateLimit, c.gameRateLimit, c.httpRateLimit, c.pinRateLimit = rateLimits[0], rateLimits[1], rateLimits[2], rateLimits[3]
+	return nil
 }
 
 func (c *Config) validate() error {
+	switch c.accessLogFormat {
+	case "common", "combined", "json":
+	default:
+		return fmt.Errorf("invalid access log format (must be common, combined, or json): %q", c.accessLogFormat)
+	}
+	if c.accessLogMaxAge < 0 || c.accessLogMaxBackups < 0 || c.accessLogMaxSize < 0 {
+		return errors.New("access log rotation limits must not be negative")
+	}
 	if (c.tlsCert == "") != (c.tlsKey == "") {
 		return errors.New("both --tls-cert and --tls-key must be provided together")
 	}
+	if _, ok := tlsVersions[c.tlsMinVersion]; !ok {
+		return fmt.Errorf("invalid tls minimum version (must be 1.2 or 1.3): %q", c.tlsMinVersion)
+	}
+	if c.tlsClientCA != "" && c.tlsCert == "" {
+		return errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+	}
+	if c.adminToken != "" && len(c.adminToken) < minAdminTokenLength {
+		return fmt.Errorf("admin token must be at least %d characters", minAdminTokenLength)
+	}
+	if c.cookieMaxAge < 0 {
+		return fmt.Errorf("invalid cookie max age (must not be negative): %s", c.cookieMaxAge)
+	}
+	if c.drainTimeout < 0 {
+		return fmt.Errorf("invalid drain timeout (must not be negative): %s", c.drainTimeout)
+	}
+	if c.gameBanDuration < 0 {
+		return fmt.Errorf("invalid game ban duration (must not be negative): %s", c.gameBanDuration)
+	}
+	switch c.moderatorFailover {
+	case failoverOff, failoverCoModerator, failoverLongestConnected, failoverVote:
+	default:
+		return fmt.Errorf("invalid moderator failover (must be off, co-moderator, longest-connected, or vote): %q", c.moderatorFailover)
+	}
+	if c.moderatorGracePeriod < 0 {
+		return fmt.Errorf("invalid moderator grace period (must not be negative): %s", c.moderatorGracePeriod)
+	}
+	if err := c.validateReloadable(); err != nil {
+		return err
+	}
+	if c.logFormat != "text" && c.logFormat != "json" {
+		return fmt.Errorf("invalid log format (must be text or json): %q", c.logFormat)
+	}
+	if c.opsAddr == "" {
+		c.opsAddr = c.metricsAddr
+	}
+	if c.opsAddr != "" && !validListenAddr(c.opsAddr) {
+		return fmt.Errorf("invalid ops address (must be host:port or unix:/path): %q", c.opsAddr)
+	}
+	if c.profile && c.opsAddr == "" {
+		return errors.New("--profile requires --ops-addr")
+	}
+	if c.redirectAddr != "" {
+		if !validListenAddr(c.redirectAddr) {
+			return fmt.Errorf("invalid redirect address (must be host:port or unix:/path): %q", c.redirectAddr)
+		}
+		if c.tlsCert == "" {
+			return errors.New("--redirect-addr requires --tls-cert and --tls-key")
+		}
+	}
+	if c.traceEndpoint != "" {
+		u, err := url.Parse(c.traceEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid trace endpoint (must be an http or https url): %q", c.traceEndpoint)
+		}
+	}
+	if c.traceSampleRatio < 0 || c.traceSampleRatio > 1 {
+		return fmt.Errorf("invalid trace sample ratio (must be between 0 and 1 inclusive): %v", c.traceSampleRatio)
+	}
+	if path, ok := c.socketPath(); ok && path == "" {
+		return errors.New("invalid bind address (unix socket path must not be empty)")
+	}
+	if _, err := parseSocketMode(c.socketMode); err != nil {
+		return err
+	}
+	for _, origin := range c.allowedOrigins {
+		if _, ok := normalizeOrigin(origin); !ok && origin != "*" {
+			return fmt.Errorf("invalid allowed origin (must be scheme://host[:port] or *): %q", origin)
+		}
+	}
+	if c.publicURL != "" {
+		u, err := url.Parse(c.publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid public url (must be an absolute http or https url): %q", c.publicURL)
+		}
+
+		path := strings.TrimSuffix(u.Path, "/")
+		switch {
+		case c.prefix == "":
+			c.prefix = path
+		case strings.TrimSuffix(c.prefix, "/") != path:
+			return fmt.Errorf("public url path %q does not match prefix %q", path, c.prefix)
+		}
+
+		u.Path = ""
+		c.baseURL = u
+	}
+	proxies, err := parseTrustedProxies(c.trustedProxies)
+	if err != nil {
+		return err
+	}
+	c.proxies = proxies
 	if c.port < 1 || c.port > 65535 {
 		return fmt.Errorf("invalid port (must be between 1-65535 inclusive): %d", c.port)
 	}
@@ -47,6 +318,36 @@ func (c *Config) scheme() string {
 	return "http"
 }
 
+// absoluteURL returns the public URL for a path, which should include the
+// prefix. Without --public-url, it is derived from the request.
+func (c *Config) absoluteURL(r *http.Request, path string) string {
+	if c.baseURL != nil {
+		return c.baseURL.String() + path
+	}
+
+	return clientScheme(r) + "://" + r.Host + path
+}
+
+// redirectURL returns the location to redirect to for a path, which should
+// include the prefix. It is only absolute if --public-url is set.
+func (c *Config) redirectURL(path string) string {
+	if c.baseURL != nil {
+		return c.baseURL.String() + path
+	}
+
+	return path
+}
+
+// cookieDomain returns the domain to scope cookies to, if --public-url is
+// set.
+func (c *Config) cookieDomain() string {
+	if c.baseURL == nil {
+		return ""
+	}
+
+	return c.baseURL.Hostname()
+}
+
 func newCmd(cfg *Config) *cobra.Command {
 	v := viper.New()
 	v.SetEnvPrefix("PARTYBOX")
@@ -59,6 +360,9 @@ func newCmd(cfg *Config) *cobra.Command {
 		Args:          cobra.ExactArgs(0),
 		SilenceErrors: true,
 		Version:       releaseVersion,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfigFile(cfg, cmd.Root().PersistentFlags())
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := cfg.validate(); err != nil {
 				return err
@@ -67,21 +371,65 @@ func newCmd(cfg *Config) *cobra.Command {
 		},
 	}
 
-	fs := cmd.Flags()
+	fs := cmd.PersistentFlags()
 
 	fs.SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		return pflag.NormalizedName(strings.ReplaceAll(name, "_", "-"))
 	})
 
-	fs.StringVarP(&cfg.bind, "bind", "b", "0.0.0.0", "address to bind to (env: PARTYBOX_BIND)")
+	fs.StringVar(&cfg.accessLog, "access-log", "", "write http access logs to this file, or - for stdout (env: PARTYBOX_ACCESS_LOG)")
+	fs.StringVar(&cfg.accessLogFormat, "access-log-format", "combined", "access log format: common, combined, or json (env: PARTYBOX_ACCESS_LOG_FORMAT)")
+	fs.DurationVar(&cfg.accessLogMaxAge, "access-log-max-age", 0, "rotate the access log file after this long, or 0 to disable (env: PARTYBOX_ACCESS_LOG_MAX_AGE)")
+	fs.IntVar(&cfg.accessLogMaxBackups, "access-log-max-backups", 0, "number of rotated access log files to keep, or 0 to keep all (env: PARTYBOX_ACCESS_LOG_MAX_BACKUPS)")
+	fs.Int64Var(&cfg.accessLogMaxSize, "access-log-max-size", 0, "rotate the access log file after this many megabytes, or 0 to disable (env: PARTYBOX_ACCESS_LOG_MAX_SIZE)")
+	fs.StringVar(&cfg.adminToken, "admin-token", "", "bearer token required by the admin api; without this or --tls-client-ca, the admin api is disabled (env: PARTYBOX_ADMIN_TOKEN)")
+	fs.StringSliceVar(&cfg.allowedOrigins, "allowed-origins", nil, "comma-separated origins, besides this server, allowed to open websocket connections, or * for any (env: PARTYBOX_ALLOWED_ORIGINS)")
+	fs.StringVarP(&cfg.configFile, "config", "c", "", "path to a yaml or toml config file, reloaded on change (env: PARTYBOX_CONFIG)")
+	fs.StringVar(&cfg.contentFilter, "content-filter", "off", "action for usernames and other text containing filtered words: off, reject, mask, or approve (env: PARTYBOX_CONTENT_FILTER)")
+	fs.BoolVar(&cfg.contentFilterBuiltin, "content-filter-builtin", true, "include the built-in wordlist in the content filter (env: PARTYBOX_CONTENT_FILTER_BUILTIN)")
+	fs.StringSliceVar(&cfg.contentFilterWordlists, "content-filter-wordlist", nil, "comma-separated paths to files of additional words to filter, one per line (env: PARTYBOX_CONTENT_FILTER_WORDLIST)")
+	fs.DurationVar(&cfg.cookieMaxAge, "cookie-max-age", 30*24*time.Hour, "lifetime of player identity cookies, or 0 for browser session cookies (env: PARTYBOX_COOKIE_MAX_AGE)")
+	fs.StringSliceVar(&cfg.cookieSecrets, "cookie-secret", nil, "comma-separated secrets used to sign player cookies; the first signs new cookies, all are accepted (env: PARTYBOX_COOKIE_SECRET)")
+	fs.StringVarP(&cfg.bind, "bind", "b", "0.0.0.0", "address to bind to, or unix:/path/to/socket (env: PARTYBOX_BIND)")
+	fs.DurationVar(&cfg.drainTimeout, "drain-timeout", 0, "time to keep serving with readiness checks failing before shutting down, unless every client leaves sooner (env: PARTYBOX_DRAIN_TIMEOUT)")
+	fs.DurationVar(&cfg.gameBanDuration, "game-ban-duration", 24*time.Hour, "time before bans issued by game moderators expire, or 0 to never expire (env: PARTYBOX_GAME_BAN_DURATION)")
+	fs.StringVar(&cfg.logFormat, "log-format", "text", "log output format: text or json (env: PARTYBOX_LOG_FORMAT)")
+	fs.StringVar(&cfg.logLevel, "log-level", "warn", "minimum log level: debug, info, warn, or error (env: PARTYBOX_LOG_LEVEL)")
+	fs.BoolVar(&cfg.maintenance, "maintenance", false, "start in maintenance mode, failing readiness checks (env: PARTYBOX_MAINTENANCE)")
+	fs.IntVar(&cfg.maxCelebrityLength, "max-celebrity-length", 64, "maximum length of a celebrity name, or 0 for no limit (env: PARTYBOX_MAX_CELEBRITY_LENGTH)")
+	fs.IntVar(&cfg.maxConnections, "max-connections", 100, "maximum websocket connections per game, or 0 for no limit (env: PARTYBOX_MAX_CONNECTIONS)")
+	fs.IntVar(&cfg.maxConnectionsPerPlayer, "max-connections-per-player", 4, "maximum websocket connections per game for a single player cookie, or 0 for no limit (env: PARTYBOX_MAX_CONNECTIONS_PER_PLAYER)")
+	fs.IntVar(&cfg.maxGames, "max-games", 1000, "maximum active games on the server, or 0 for no limit (env: PARTYBOX_MAX_GAMES)")
+	fs.IntVar(&cfg.maxGamesPerIP, "max-games-per-ip", 20, "maximum active games created from a single ip address, or 0 for no limit (env: PARTYBOX_MAX_GAMES_PER_IP)")
+	fs.IntVar(&cfg.maxPlayers, "max-players", 50, "maximum players per game, or 0 for no limit (env: PARTYBOX_MAX_PLAYERS)")
+	fs.IntVar(&cfg.maxUsernameLength, "max-username-length", 32, "maximum length of a username, or 0 for no limit (env: PARTYBOX_MAX_USERNAME_LENGTH)")
+	fs.BoolVar(&cfg.metrics, "metrics", false, "expose prometheus metrics at /metrics (env: PARTYBOX_METRICS)")
+	fs.StringVar(&cfg.metricsAddr, "metrics-addr", "", "deprecated alias for --ops-addr (env: PARTYBOX_METRICS_ADDR)")
+	fs.StringVar(&cfg.moderatorFailover, "moderator-failover", "longest-connected", "who becomes moderator when the host has been disconnected for the grace period: off, co-moderator, longest-connected, or vote (env: PARTYBOX_MODERATOR_FAILOVER)")
+	fs.DurationVar(&cfg.moderatorGracePeriod, "moderator-grace-period", 2*time.Minute, "time a disconnected host keeps moderator rights before they pass to someone else (env: PARTYBOX_MODERATOR_GRACE_PERIOD)")
+	fs.StringVar(&cfg.opsAddr, "ops-addr", "", "private host:port or unix:/path serving metrics, profiling, and the admin api (env: PARTYBOX_OPS_ADDR)")
 	fs.DurationVar(&cfg.playerTimeout, "player-timeout", 10*time.Minute, "time before idle players are kicked (env: PARTYBOX_IDLE_PLAYER_TIMEOUT)")
 	fs.IntVarP(&cfg.port, "port", "p", 8080, "port to listen on (env: PARTYBOX_PORT)")
 	fs.StringVar(&cfg.prefix, "prefix", "", "path to prepend to all URLs, for use behind reverse proxy (env: PARTYBOX_PREFIX)")
-	fs.BoolVar(&cfg.profile, "profile", false, "register net/http/pprof handlers (env: PARTYBOX_PROFILE)")
+	fs.BoolVar(&cfg.profile, "profile", false, "register net/http/pprof handlers on the ops listener (env: PARTYBOX_PROFILE)")
+	fs.StringVar(&cfg.publicURL, "public-url", "", "public base url, e.g. https://games.example.com/partybox, used for absolute links and cookies (env: PARTYBOX_PUBLIC_URL)")
+	fs.StringVar(&cfg.rateLimitCommands, "rate-limit-commands", "10/1s", "websocket commands allowed per client, as n/interval, or 0 to disable (env: PARTYBOX_RATE_LIMIT_COMMANDS)")
+	fs.StringVar(&cfg.rateLimitGames, "rate-limit-games", "30/1h", "new games allowed per ip address, as n/interval, or 0 to disable (env: PARTYBOX_RATE_LIMIT_GAMES)")
+	fs.StringVar(&cfg.rateLimitHTTP, "rate-limit-http", "600/1m", "http requests allowed per ip address, as n/interval, or 0 to disable (env: PARTYBOX_RATE_LIMIT_HTTP)")
+	fs.StringVar(&cfg.rateLimitPIN, "rate-limit-pin", "5/1m", "lobby pin attempts allowed per ip address, as n/interval, or 0 to disable (env: PARTYBOX_RATE_LIMIT_PIN)")
+	fs.StringVar(&cfg.redirectAddr, "redirect-addr", "", "plain http host:port which redirects all requests to https (env: PARTYBOX_REDIRECT_ADDR)")
 	fs.DurationVar(&cfg.sessionTimeout, "session-timeout", 60*time.Minute, "time before idle game sessions are ended (env: PARTYBOX_IDLE_SESSION_TIMEOUT)")
+	fs.StringVar(&cfg.socketMode, "socket-mode", "0660", "file mode for the unix socket when binding to unix:/path (env: PARTYBOX_SOCKET_MODE)")
+	fs.StringVar(&cfg.store, "store", "", "path to a json file used to persist bans across restarts, disabled if empty (env: PARTYBOX_STORE)")
 	fs.StringVar(&cfg.tlsCert, "tls-cert", "", "path to tls certificate (env: PARTYBOX_TLS_CERT)")
+	fs.StringVar(&cfg.tlsClientCA, "tls-client-ca", "", "path to a ca bundle used to require client certificates for admin endpoints (env: PARTYBOX_TLS_CLIENT_CA)")
 	fs.StringVar(&cfg.tlsKey, "tls-key", "", "path to tls keyfile (env: PARTYBOX_TLS_KEY)")
-	fs.BoolVarP(&cfg.verbose, "verbose", "v", false, "display additional output (env: PARTYBOX_VERBOSE)")
+	fs.StringVar(&cfg.tlsMinVersion, "tls-min-version", "1.2", "minimum tls version to accept: 1.2 or 1.3 (env: PARTYBOX_TLS_MIN_VERSION)")
+	fs.StringVar(&cfg.traceEndpoint, "trace-endpoint", "", "base url of an otlp/http collector to export traces to, disabled if empty (env: PARTYBOX_TRACE_ENDPOINT)")
+	fs.Float64Var(&cfg.traceSampleRatio, "trace-sample-ratio", 1, "fraction of new traces to sample, between 0 and 1 (env: PARTYBOX_TRACE_SAMPLE_RATIO)")
+	fs.StringVar(&cfg.traceServiceName, "trace-service-name", "partybox", "service name reported with exported traces (env: PARTYBOX_TRACE_SERVICE_NAME)")
+	fs.StringSliceVar(&cfg.trustedProxies, "trusted-proxies", nil, "comma-separated ip addresses or cidr ranges of reverse proxies whose forwarded headers are trusted (env: PARTYBOX_TRUSTED_PROXIES)")
+	fs.BoolVarP(&cfg.verbose, "verbose", "v", false, "shorthand for --log-level=info (env: PARTYBOX_VERBOSE)")
 	fs.BoolVarP(&cfg.version, "version", "V", false, "display version and exit (env: PARTYBOX_VERSION)")
 
 	fs.VisitAll(func(f *pflag.Flag) {
@@ -92,6 +440,18 @@ func newCmd(cfg *Config) *cobra.Command {
 		}
 	})
 
+	// Fall back to the standard OpenTelemetry environment variables.
+	if endpoint := cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); endpoint != "" && !v.IsSet("trace-endpoint") {
+		_ = fs.Set("trace-endpoint", endpoint)
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" && !v.IsSet("trace-service-name") {
+		_ = fs.Set("trace-service-name", name)
+	}
+
+	_ = fs.MarkDeprecated("metrics-addr", "use --ops-addr instead")
+
+	cmd.AddCommand(newHealthCheckCmd(cfg))
+
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 	cmd.SetHelpCommand(&cobra.Command{Hidden: true})
 	cmd.SetVersionTemplate("partybox v{{.Version}}\n")
@@ -0,0 +1,374 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// reloadableFlags lists the options which may be changed by editing the
+// config file while the server is running. Anything else is reported and
+// ignored until the next restart.
+var reloadableFlags = []string{
+	"content-filter",
+	"content-filter-builtin",
+	"content-filter-wordlist",
+	"cookie-secret",
+	"log-level",
+	"max-celebrity-length",
+	"max-connections",
+	"max-connections-per-player",
+	"max-games",
+	"max-games-per-ip",
+	"max-players",
+	"max-username-length",
+	"player-timeout",
+	"rate-limit-commands",
+	"rate-limit-games",
+	"rate-limit-http",
+	"rate-limit-pin",
+	"session-timeout",
+	"verbose",
+}
+
+// secretFlags lists the options whose values must never be logged.
+var secretFlags = []string{
+	"cookie-secret",
+}
+
+// GameSettings holds the options which can be overridden per game type
+// under the games section of the config file.
+type GameSettings struct {
+	PlayerTimeout  time.Duration `mapstructure:"player-timeout"`
+	SessionTimeout time.Duration `mapstructure:"session-timeout"`
+}
+
+var logLevel = new(slog.LevelVar)
+
+type configFile struct {
+	path   string
+	fs     *pflag.FlagSet
+	pinned map[string]bool // set via command-line flag or environment variable
+
+	mu     sync.Mutex        // serializes reloads
+	warned map[string]string // last value reported as requiring a restart
+}
+
+// loadConfigFile applies options from the config file to every flag that
+// was not already set on the command line or via the environment.
+func loadConfigFile(cfg *Config, fs *pflag.FlagSet) error {
+	if cfg.configFile == "" {
+		return nil
+	}
+
+	cf := &configFile{
+		path:   cfg.configFile,
+		fs:     fs,
+		pinned: make(map[string]bool),
+		warned: make(map[string]string),
+	}
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		cf.pinned[f.Name] = f.Changed
+	})
+
+	v, err := cf.read()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range v.AllKeys() {
+		name := strings.SplitN(key, ".", 2)[0]
+		if name != "games" && name != "bans" && fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown option in config file: %q", key)
+		}
+	}
+
+	var setErr error
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		if setErr != nil || cf.pinned[f.Name] || f.Name == "config" || !v.InConfig(f.Name) {
+			return
+		}
+
+		if err := fs.Set(f.Name, configValue(v, f.Name)); err != nil {
+			setErr = fmt.Errorf("invalid value for %q in config file: %w", f.Name, err)
+		}
+	})
+	if setErr != nil {
+		return setErr
+	}
+
+	games, err := readGameSettings(v)
+	if err != nil {
+		return err
+	}
+
+	configured, err := readBans(v.Get("bans"))
+	if err != nil {
+		return err
+	}
+
+	cfg.games = games
+	cfg.configLoader = cf
+	bans.setConfigured(configured)
+
+	return nil
+}
+
+func (cf *configFile) read() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(cf.path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	return v, nil
+}
+
+// flagValue returns the current value of a flag in the same form as
+// normalizeValue, so that the two can be compared.
+func flagValue(f *pflag.Flag) string {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return strings.Join(sv.GetSlice(), ",")
+	}
+
+	return f.Value.String()
+}
+
+// normalizeValue returns a value from the config file in the form the flag
+// would print it, e.g. 10m0s for a duration of 10m, by setting it on a
+// scratch copy of the flag. Values the flag does not accept are returned
+// unchanged, to be reported when they are applied.
+func normalizeValue(f *pflag.Flag, value string) string {
+	if _, ok := f.Value.(pflag.SliceValue); ok {
+		return strings.Trim(value, "[]")
+	}
+
+	scratch, ok := reflect.New(reflect.TypeOf(f.Value).Elem()).Interface().(pflag.Value)
+	if !ok || scratch.Set(value) != nil {
+		return value
+	}
+
+	return scratch.String()
+}
+
+// setFlag sets a flag from a config value, replacing rather than appending
+// to the contents of slice flags.
+func setFlag(fs *pflag.FlagSet, name, value string) error {
+	if sv, ok := fs.Lookup(name).Value.(pflag.SliceValue); ok {
+		var list []string
+		if value != "" {
+			list = strings.Split(value, ",")
+		}
+
+		return sv.Replace(list)
+	}
+
+	return fs.Set(name, value)
+}
+
+func redact(name, value string) string {
+	if slices.Contains(secretFlags, name) && value != "" {
+		return "[redacted]"
+	}
+
+	return value
+}
+
+func configValue(v *viper.Viper, key string) string {
+	switch val := v.Get(key).(type) {
+	case []any:
+		parts := make([]string, 0, len(val))
+		for _, p := range val {
+			parts = append(parts, fmt.Sprint(p))
+		}
+
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func readGameSettings(v *viper.Viper) (map[string]GameSettings, error) {
+	games := make(map[string]GameSettings)
+
+	if !v.IsSet("games") {
+		return games, nil
+	}
+
+	if err := v.UnmarshalKey("games", &games); err != nil {
+		return nil, fmt.Errorf("invalid games section in config file: %w", err)
+	}
+
+	for name, g := range games {
+		if g.PlayerTimeout < 0 || g.SessionTimeout < 0 {
+			return nil, fmt.Errorf("invalid settings for game %q: timeouts must not be negative", name)
+		}
+	}
+
+	return games, nil
+}
+
+// watch reloads the config file whenever it changes, until ctx is done.
+func (cf *configFile) watch(ctx context.Context, cfg *Config) {
+	v := viper.New()
+	v.SetConfigFile(cf.path)
+
+	if err := v.ReadInConfig(); err != nil {
+		slog.Error("Unable to watch config file", "path", cf.path, "error", err)
+
+		return
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		cf.reload(cfg)
+	})
+	v.WatchConfig()
+
+	slog.Info("Watching config file for changes", "path", cf.path)
+}
+
+// reload re-reads the config file, applying changes to reloadable options
+// and reporting any others. No changes are applied if the file is invalid.
+func (cf *configFile) reload(cfg *Config) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	v, err := cf.read()
+	if err != nil {
+		slog.Error("Failed to reload config file", "path", cf.path, "error", err)
+
+		return
+	}
+
+	// Editors often truncate a file before writing the new contents.
+	if len(v.AllKeys()) == 0 {
+		slog.Debug("Config file is empty, ignoring", "path", cf.path)
+
+		return
+	}
+
+	games, err := readGameSettings(v)
+	if err != nil {
+		slog.Error("Failed to reload config file", "path", cf.path, "error", err)
+
+		return
+	}
+
+	configured, err := readBans(v.Get("bans"))
+	if err != nil {
+		slog.Error("Failed to reload config file", "path", cf.path, "error", err)
+
+		return
+	}
+
+	type change struct {
+		name, old, new string
+	}
+
+	var changes []change
+
+	cf.fs.VisitAll(func(f *pflag.Flag) {
+		if cf.pinned[f.Name] || f.Name == "config" {
+			return
+		}
+
+		value := f.DefValue
+		if v.InConfig(f.Name) {
+			value = configValue(v, f.Name)
+		}
+		value = normalizeValue(f, value)
+
+		if value == flagValue(f) {
+			delete(cf.warned, f.Name)
+
+			return
+		}
+
+		if !slices.Contains(reloadableFlags, f.Name) {
+			if cf.warned[f.Name] != value {
+				slog.Warn("Config file change requires a restart, ignoring", "option", f.Name, "value", redact(f.Name, value))
+				cf.warned[f.Name] = value
+			}
+
+			return
+		}
+
+		changes = append(changes, change{name: f.Name, old: flagValue(f), new: value})
+	})
+
+	cfg.mu.Lock()
+
+	var applyErr error
+
+	for i, c := range changes {
+		if err := setFlag(cf.fs, c.name, c.new); err != nil {
+			applyErr = fmt.Errorf("invalid value for %q: %w", c.name, err)
+			changes = changes[:i]
+
+			break
+		}
+	}
+
+	if applyErr == nil {
+		applyErr = cfg.validateReloadable()
+	}
+
+	// Wordlists are re-read on every reload, so that edits to them are
+	// picked up along with the config file.
+	var filter *contentFilter
+	if applyErr == nil {
+		filter, applyErr = newContentFilter(cfg)
+	}
+
+	if applyErr != nil {
+		for _, c := range changes {
+			_ = setFlag(cf.fs, c.name, c.old)
+		}
+		_ = cfg.validateReloadable()
+		cfg.mu.Unlock()
+
+		slog.Error("Failed to reload config file", "path", cf.path, "error", applyErr)
+
+		return
+	}
+
+	gamesChanged := !maps.Equal(cfg.games, games)
+	cfg.games = games
+	cfg.filter = filter
+	cfg.mu.Unlock()
+
+	applyLogLevel(cfg)
+
+	bansChanged := bans.setConfigured(configured)
+
+	for _, c := range changes {
+		slog.Info("Reloaded option from config file", "option", c.name, "old", redact(c.name, c.old), "new", redact(c.name, c.new))
+	}
+	if gamesChanged {
+		slog.Info("Reloaded per-game settings from config file")
+	}
+	if bansChanged {
+		slog.Info("Reloaded bans from config file", "count", len(configured))
+	}
+}
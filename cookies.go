@@ -0,0 +1,140 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	playerCookieName = "partybox_id"
+
+	// minCookieSecretLength is the shortest accepted --cookie-secret.
+	minCookieSecretLength = 32
+)
+
+var errInvalidCookie = errors.New("invalid signature")
+
+// cookieKeys returns the keys used to sign and verify cookies. The first key
+// signs new cookies, and any of them is accepted, so that secrets can be
+// rotated without logging everybody out.
+func (c *Config) cookieKeys() [][]byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.cookieSecrets) == 0 {
+		return [][]byte{c.cookieFallbackKey}
+	}
+
+	keys := make([][]byte, 0, len(c.cookieSecrets))
+	for _, s := range c.cookieSecrets {
+		keys = append(keys, []byte(s))
+	}
+
+	return keys
+}
+
+// signValue returns the signature of a named value. The name is included
+// so that a value signed for one purpose cannot be used for another.
+func signValue(key []byte, name, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signCookie(key []byte, payload string) string {
+	return signValue(key, playerCookieName, payload)
+}
+
+// verifyCookie checks a cookie of the form id.issued.signature, returning
+// the player ID, when it was issued, and whether it was signed with the
+// current key.
+func verifyCookie(keys [][]byte, value string) (id string, issued time.Time, current bool, err error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", time.Time{}, false, errInvalidCookie
+	}
+
+	id, ts, sig := parts[0], parts[1], parts[2]
+	payload := id + "." + ts
+
+	for i, key := range keys {
+		if !hmac.Equal([]byte(sig), []byte(signCookie(key, payload))) {
+			continue
+		}
+
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return "", time.Time{}, false, errInvalidCookie
+		}
+
+		return id, time.Unix(unix, 0), i == 0, nil
+	}
+
+	return "", time.Time{}, false, errInvalidCookie
+}
+
+func setPlayerCookie(cfg *Config, w http.ResponseWriter, r *http.Request, id string, issued time.Time) {
+	payload := id + "." + strconv.FormatInt(issued.Unix(), 10)
+
+	cookie := &http.Cookie{
+		Name:     playerCookieName,
+		Value:    payload + "." + signCookie(cfg.cookieKeys()[0], payload),
+		Path:     cfg.prefix + "/",
+		Domain:   cfg.cookieDomain(),
+		Secure:   clientScheme(r) == "https" || (cfg.baseURL != nil && cfg.baseURL.Scheme == "https"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	if cfg.cookieMaxAge > 0 {
+		cookie.Expires = issued.Add(cfg.cookieMaxAge)
+		cookie.MaxAge = int(time.Until(cookie.Expires).Seconds())
+	}
+
+	http.SetCookie(w, cookie)
+}
+
+func getOrSetPlayerID(cfg *Config, w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(playerCookieName); err == nil && c.Value != "" {
+		id, issued, current, err := verifyCookie(cfg.cookieKeys(), c.Value)
+
+		switch {
+		case err != nil:
+			slog.Warn("Rejected player cookie", "ip", realIP(r), "error", err)
+		case cfg.cookieMaxAge > 0 && time.Since(issued) > cfg.cookieMaxAge:
+			slog.Debug("Player cookie expired", "player_id", id, "ip", realIP(r))
+		case !current:
+			// Re-sign cookies issued with a previous secret.
+			setPlayerCookie(cfg, w, r, id, issued)
+
+			return id
+		default:
+			return id
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		slog.Error("Failed to generate player ID", "ip", realIP(r), "error", err)
+		return ""
+	}
+	id := hex.EncodeToString(buf)
+
+	setPlayerCookie(cfg, w, r, id, time.Now())
+
+	return id
+}
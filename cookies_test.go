@@ -0,0 +1,119 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	testCookieKey = []byte("0123456789abcdef0123456789abcdef")
+	oldCookieKey  = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func testCookie(key []byte, id string, issued time.Time) string {
+	payload := id + "." + strconv.FormatInt(issued.Unix(), 10)
+
+	return payload + "." + signCookie(key, payload)
+}
+
+func TestVerifyCookie(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	keys := [][]byte{testCookieKey, oldCookieKey}
+	valid := testCookie(testCookieKey, "abc", issued)
+
+	tests := []struct {
+		name    string
+		value   string
+		id      string
+		current bool
+		wantErr bool
+	}{
+		{"current key", valid, "abc", true, false},
+		{"previous key", testCookie(oldCookieKey, "abc", issued), "abc", false, false},
+		{"unknown key", testCookie([]byte("another secret of thirty-two b.."), "abc", issued), "", false, true},
+		{"changed id", "abd" + valid[3:], "", false, true},
+		{"changed time", "abc.1700000001" + valid[len("abc.1700000000"):], "", false, true},
+		{"unsigned", "abc.1700000000", "", false, true},
+		{"extra part", valid + ".x", "", false, true},
+		{"empty", "", "", false, true},
+		{"invalid time", "abc.x." + signCookie(testCookieKey, "abc.x"), "", false, true},
+		{"signed for an invite", "abc.1700000000." + signValue(testCookieKey, inviteParam, "abc.1700000000"), "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, got, current, err := verifyCookie(keys, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if id != tt.id || current != tt.current || !got.Equal(issued) {
+				t.Errorf("verifyCookie = %q, %s, %v, want %q, %s, %v", id, got, current, tt.id, issued, tt.current)
+			}
+		})
+	}
+}
+
+func TestGetOrSetPlayerID(t *testing.T) {
+	cfg := &Config{
+		cookieSecrets: []string{string(testCookieKey), string(oldCookieKey)},
+		cookieMaxAge:  24 * time.Hour,
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		cookie string
+		keep   bool // the cookie's player ID is kept
+		set    bool // a cookie is set in the response
+	}{
+		{"none", "", false, true},
+		{"valid", testCookie(testCookieKey, "abc", now), true, false},
+		{"previous key", testCookie(oldCookieKey, "abc", now), true, true},
+		{"expired", testCookie(testCookieKey, "abc", now.Add(-48*time.Hour)), false, true},
+		{"forged", "abc.1700000000.AAAA", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: playerCookieName, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			id := getOrSetPlayerID(cfg, w, r)
+			if (id == "abc") != tt.keep || id == "" {
+				t.Errorf("player ID = %q, want kept %v", id, tt.keep)
+			}
+
+			cookies := w.Result().Cookies()
+			if (len(cookies) > 0) != tt.set {
+				t.Fatalf("set %d cookies, want set %v", len(cookies), tt.set)
+			}
+			if !tt.set {
+				return
+			}
+
+			c := cookies[0]
+			if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+				t.Errorf("cookie attributes = %+v", c)
+			}
+
+			got, _, current, err := verifyCookie(cfg.cookieKeys(), c.Value)
+			if err != nil || got != id || !current {
+				t.Errorf("new cookie = %q, %v, %v, want %q signed with the current key", got, current, err, id)
+			}
+		})
+	}
+}
@@ -6,28 +6,88 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"strings"
-	"time"
 )
 
-func logf(cfg *Config, format string, args ...any) {
-	if !cfg.verbose {
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+
+	err := l.UnmarshalText([]byte(level))
+
+	return l, err
+}
+
+// effectiveLogLevel returns the configured level, lowered to info if
+// --verbose is set. The caller must hold cfg.mu.
+func effectiveLogLevel(cfg *Config) (slog.Level, error) {
+	level, err := parseLogLevel(cfg.logLevel)
+	if err != nil {
+		return level, fmt.Errorf("invalid log level %q: %w", cfg.logLevel, err)
+	}
+
+	if cfg.verbose && level > slog.LevelInfo {
+		level = slog.LevelInfo
+	}
+
+	return level, nil
+}
+
+// applyLogLevel updates the level of the running logger after a reload.
+func applyLogLevel(cfg *Config) {
+	cfg.mu.RLock()
+	level, err := effectiveLogLevel(cfg)
+	cfg.mu.RUnlock()
+
+	if err != nil {
 		return
 	}
 
-	log.Printf("%s | "+format, append([]any{time.Now().Format(logDate)}, args...)...)
+	logLevel.Set(level)
+}
+
+// newLogger builds the process-wide logger from the configured level and
+// format. --verbose is retained as shorthand for --log-level=info.
+func newLogger(cfg *Config, w io.Writer) (*slog.Logger, error) {
+	cfg.mu.RLock()
+	level, err := effectiveLogLevel(cfg)
+	cfg.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	logLevel.Set(level)
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+
+	switch cfg.logFormat {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format (must be text or json): %q", cfg.logFormat)
+	}
+}
+
+// drainErrors logs errors reported by handlers until the channel is closed.
+func drainErrors(errs <-chan error) {
+	for err := range errs {
+		slog.Error("Error serving request", "error", err)
+	}
 }
 
-func newPage(title, body string) string {
+func newPage(cfg *Config, title, body string) string {
 	var htmlBody strings.Builder
 
 	htmlBody.WriteString(`<!DOCTYPE html><html lang="en"><head>`)
-	htmlBody.WriteString(getFavicon())
+	htmlBody.WriteString(getFavicon(cfg))
 	htmlBody.WriteString(`<style>`)
 	htmlBody.WriteString(`html,body,a{display:block;height:100%;width:100%;text-decoration:none;color:inherit;cursor:auto;}</style>`)
 	htmlBody.WriteString(fmt.Sprintf("<title>%s</title></head>", title))
-	htmlBody.WriteString(fmt.Sprintf("<body><a href=\"/\">%s</a></body></html>", body))
+	htmlBody.WriteString(fmt.Sprintf("<body><a href=\"%s/\">%s</a></body></html>", cfg.prefix, body))
 
 	return htmlBody.String()
 }
@@ -6,6 +6,8 @@ package main
 
 import (
 	"embed"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -15,12 +17,26 @@ import (
 //go:embed favicons/*
 var favicons embed.FS
 
-func getFavicon() string {
-	return `<link rel="apple-touch-icon" sizes="180x180" href="/favicons/apple-touch-icon.png">
-	<link rel="icon" type="image/png" sizes="32x32" href="/favicons/favicon-96x96.png">
-	<link rel="manifest" href="/favicons/site.webmanifest" crossorigin="use-credentials">
+func getFavicon(cfg *Config) string {
+	return fmt.Sprintf(`<link rel="apple-touch-icon" sizes="180x180" href="%[1]s/favicons/apple-touch-icon.png">
+	<link rel="icon" type="image/png" sizes="32x32" href="%[1]s/favicons/favicon-96x96.png">
+	<link rel="manifest" href="%[1]s/favicons/site.webmanifest" crossorigin="use-credentials">
 	<meta name="msapplication-TileColor" content="#da532c">
-	<meta name="theme-color" content="#ffffff">`
+	<meta name="theme-color" content="#ffffff">`, cfg.prefix)
+}
+
+// webManifest returns the web app manifest, with start_url and scope set to
+// the public location of the app.
+func webManifest(cfg *Config, r *http.Request, data []byte) ([]byte, error) {
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	manifest["start_url"] = cfg.absoluteURL(r, cfg.prefix+"/")
+	manifest["scope"] = cfg.absoluteURL(r, cfg.prefix+"/")
+
+	return json.MarshalIndent(manifest, "", "  ")
 }
 
 func serveFavicons(cfg *Config, errs chan<- error) httprouter.Handle {
@@ -32,6 +48,17 @@ func serveFavicons(cfg *Config, errs chan<- error) httprouter.Handle {
 			return
 		}
 
+		if fname == "favicons/site.webmanifest" {
+			data, err = webManifest(cfg, r, data)
+			if err != nil {
+				errs <- err
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/manifest+json")
+		}
+
 		securityHeaders(cfg, w)
 
 		_, err = w.Write(data)
@@ -0,0 +1,354 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed filter/wordlist.txt
+var builtinWordlist string
+
+// Actions taken when user-supplied text matches the content filter.
+const (
+	filterOff     = "off"
+	filterReject  = "reject"
+	filterMask    = "mask"
+	filterApprove = "approve"
+)
+
+// textFilter finds objectionable text. Implementations must be safe for
+// concurrent use.
+type textFilter interface {
+	// find returns the rune offsets [start, end) of each match in s.
+	find(s string) [][2]int
+}
+
+// leetspeak maps characters commonly substituted for letters back to them.
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'i',
+	'+': 't',
+}
+
+// normalizeRune lower-cases letters and undoes leetspeak, reporting false
+// for anything which separates words.
+func normalizeRune(r rune) (rune, bool) {
+	if l, ok := leetspeak[r]; ok {
+		return l, true
+	}
+
+	if unicode.IsLetter(r) {
+		return unicode.ToLower(r), true
+	}
+
+	return 0, false
+}
+
+// foldRune undoes evasions of the filter in a single rune of text, using the
+// same normalization as names: NFKC (e.g. for fullwidth letters), removal of
+// invisible formatting characters, and confusable skeletons (e.g. for
+// Cyrillic lookalikes). Accents are dropped, and runes which separate words
+// are returned as is. It works a rune at a time, so that matches can be
+// located in the original text.
+func foldRune(r rune) []rune {
+	if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Variation_Selector, r) {
+		return nil
+	}
+
+	var folded []rune
+
+	for _, c := range norm.NFKC.String(string(r)) {
+		if _, ok := leetspeak[c]; ok {
+			folded = append(folded, c)
+			continue
+		}
+
+		for _, s := range skeleton(string(unicode.ToLower(c))) {
+			if !unicode.Is(unicode.Mn, s) {
+				folded = append(folded, s)
+			}
+		}
+	}
+
+	return folded
+}
+
+// normalizeText folds and normalizes a rune of text, reporting false if it
+// separates words. Invisible runes are returned empty, and do not.
+func normalizeText(r rune) ([]rune, bool) {
+	folded := foldRune(r)
+
+	out := make([]rune, 0, len(folded))
+	for _, c := range folded {
+		n, ok := normalizeRune(c)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, n)
+	}
+
+	return out, true
+}
+
+// collapseRepeats squeezes runs of the same letter, so that "fuuuck" and
+// "fuck" compare equal.
+func collapseRepeats(s string) string {
+	var b strings.Builder
+
+	var last rune
+	for i, r := range s {
+		if i > 0 && r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+
+	return b.String()
+}
+
+type filterToken struct {
+	text       string
+	start, end int
+
+	// trimmed is text without any trailing punctuation read as leetspeak,
+	// e.g. for "ass!".
+	trimmed string
+}
+
+// tokenize splits s into normalized words, with their rune offsets in s.
+// Runs of single letters are joined, so that spacing out a word such as
+// "f u c k" or "f.u.c.k" does not evade the filter.
+func tokenize(s string) []filterToken {
+	var tokens []filterToken
+
+	var cur strings.Builder
+	start, punct := -1, 0
+
+	runes := []rune(s)
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) {
+			if n, ok := normalizeText(runes[i]); ok {
+				if len(n) == 0 {
+					continue
+				}
+				if start < 0 {
+					start = i
+				}
+				cur.WriteString(string(n))
+
+				if unicode.IsPunct(runes[i]) || unicode.IsSymbol(runes[i]) {
+					punct++
+				} else {
+					punct = 0
+				}
+
+				continue
+			}
+		}
+
+		if start >= 0 {
+			text := cur.String()
+			tokens = append(tokens, filterToken{
+				text:    text,
+				start:   start,
+				end:     i,
+				trimmed: text[:len(text)-punct],
+			})
+			cur.Reset()
+			start, punct = -1, 0
+		}
+	}
+
+	joined := tokens[:0]
+	spelled := false
+
+	for _, t := range tokens {
+		single := utf8.RuneCountInString(t.text) == 1
+
+		if single && spelled {
+			last := &joined[len(joined)-1]
+			last.text += t.text
+			last.trimmed = last.text
+			last.end = t.end
+
+			continue
+		}
+
+		joined = append(joined, t)
+		spelled = single
+	}
+
+	return joined
+}
+
+// wordFilter matches whole words against a wordlist, after undoing
+// leetspeak, spacing, and repeated letters.
+type wordFilter struct {
+	words    map[string]int // collapsed word -> length of the original
+	prefixes map[string]int
+}
+
+func newWordFilter() *wordFilter {
+	return &wordFilter{
+		words:    make(map[string]int),
+		prefixes: make(map[string]int),
+	}
+}
+
+// load adds the words from a wordlist, in the format of
+// filter/wordlist.txt.
+func (f *wordFilter) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target := f.words
+		if strings.HasSuffix(line, "*") {
+			target = f.prefixes
+			line = strings.TrimSuffix(line, "*")
+		}
+
+		var b strings.Builder
+		for _, r := range line {
+			if n, ok := normalizeText(r); ok {
+				b.WriteString(string(n))
+			}
+		}
+
+		word := b.String()
+		if word == "" {
+			continue
+		}
+
+		collapsed := collapseRepeats(word)
+		if n, ok := target[collapsed]; !ok || len(word) < n {
+			target[collapsed] = len(word)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (f *wordFilter) find(s string) [][2]int {
+	var matches [][2]int
+
+	for _, t := range tokenize(s) {
+		if f.matches(t.text) || f.matches(t.trimmed) {
+			matches = append(matches, [2]int{t.start, t.end})
+		}
+	}
+
+	return matches
+}
+
+func (f *wordFilter) matches(word string) bool {
+	if word == "" {
+		return false
+	}
+
+	collapsed := collapseRepeats(word)
+
+	// Requiring the word to be at least as long as the listed one avoids
+	// matching e.g. "as" against "ass".
+	if n, ok := f.words[collapsed]; ok && len(word) >= n {
+		return true
+	}
+
+	for prefix, n := range f.prefixes {
+		if strings.HasPrefix(collapsed, prefix) && len(word) >= n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contentFilter applies the configured action to text matched by a
+// textFilter. A nil *contentFilter allows everything.
+type contentFilter struct {
+	action string
+	filter textFilter
+}
+
+func newContentFilter(cfg *Config) (*contentFilter, error) {
+	if cfg.contentFilter == filterOff {
+		return nil, nil
+	}
+
+	wf := newWordFilter()
+
+	if cfg.contentFilterBuiltin {
+		if err := wf.load(strings.NewReader(builtinWordlist)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range cfg.contentFilterWordlists {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open wordlist: %w", err)
+		}
+
+		err = wf.load(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read wordlist %q: %w", path, err)
+		}
+	}
+
+	return &contentFilter{
+		action: cfg.contentFilter,
+		filter: wf,
+	}, nil
+}
+
+// check reports whether s contains filtered text, returning s with any
+// matches masked.
+func (cf *contentFilter) check(s string) (string, bool) {
+	if cf == nil {
+		return s, false
+	}
+
+	matches := cf.filter.find(s)
+	if len(matches) == 0 {
+		return s, false
+	}
+
+	runes := []rune(s)
+	for _, m := range matches {
+		for i := m[0]; i < m[1]; i++ {
+			if !unicode.IsSpace(runes[i]) {
+				runes[i] = '*'
+			}
+		}
+	}
+
+	return string(runes), true
+}
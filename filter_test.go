@@ -0,0 +1,102 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestFilter(t *testing.T) *contentFilter {
+	t.Helper()
+
+	wf := newWordFilter()
+	if err := wf.load(strings.NewReader(builtinWordlist)); err != nil {
+		t.Fatal(err)
+	}
+
+	return &contentFilter{action: filterMask, filter: wf}
+}
+
+func TestContentFilterCheck(t *testing.T) {
+	cf := newTestFilter(t)
+
+	tests := []struct {
+		in     string
+		masked string
+		match  bool
+	}{
+		{"Madonna", "Madonna", false},
+		{"fuck", "****", true},
+		{"FUCK", "****", true},
+		{"fuuuck", "******", true},
+		{"fuckface", "********", true},
+		{"f u c k", "* * * *", true},
+		{"f.u.c.k", "*******", true},
+		{"sh1t", "****", true},
+		{"a$$", "***", true},
+		{"ass!", "****", true},
+		{"as", "as", false},
+		{"Bass player", "Bass player", false},
+		{"Scunthorpe", "Scunthorpe", false},
+
+		// Evasions undone by normalization.
+		{"ｆｕｃｋ", "****", true},
+		{"fu\u200bck", "*****", true},
+		{"\u0455hit", "****", true},
+		{"f\u00fcck", "****", true},
+		{"fu\u0308ck", "*****", true},
+
+		// Prefixes must not match real names.
+		{"Camille Pissarro", "Camille Pissarro", false},
+		{"CeCe Peniston", "CeCe Peniston", false},
+		{"piss off", "**** off", true},
+		{"pissed", "******", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			masked, matched := cf.check(tt.in)
+			if matched != tt.match || masked != tt.masked {
+				t.Errorf("check(%q) = %q, %v, want %q, %v", tt.in, masked, matched, tt.masked, tt.match)
+			}
+		})
+	}
+}
+
+func TestWordFilterLoad(t *testing.T) {
+	wf := newWordFilter()
+
+	list := "# comment\n\nHeck\ndarn*\nｇｏｓｈ\n"
+	if err := wf.load(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in    string
+		match bool
+	}{
+		{"heck", true},
+		{"heckle", false},
+		{"darn", true},
+		{"darnation", true},
+		{"gosh", true},
+		{"comment", false},
+	}
+
+	for _, tt := range tests {
+		if got := len(wf.find(tt.in)) > 0; got != tt.match {
+			t.Errorf("find(%q) matched = %v, want %v", tt.in, got, tt.match)
+		}
+	}
+}
+
+func TestNilContentFilter(t *testing.T) {
+	var cf *contentFilter
+
+	if masked, matched := cf.check("fuck"); matched || masked != "fuck" {
+		t.Errorf("nil filter check = %q, %v, want input unchanged", masked, matched)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// serverStatus tracks process-wide state reported by the health endpoints.
+type serverStatus struct {
+	startedAt   time.Time
+	draining    atomic.Bool
+	maintenance atomic.Bool
+	games       []*GameManager
+	store       *Store
+}
+
+func newServerStatus(cfg *Config) *serverStatus {
+	st := &serverStatus{
+		startedAt: time.Now(),
+	}
+	st.maintenance.Store(cfg.maintenance)
+
+	return st
+}
+
+// drainPollInterval is how often draining checks for remaining clients.
+const drainPollInterval = 250 * time.Millisecond
+
+// clients returns the number of clients connected to any game.
+func (st *serverStatus) clients() int {
+	total := 0
+
+	for _, gm := range st.games {
+		_, clients := gm.counts()
+		total += clients
+	}
+
+	return total
+}
+
+// drain waits until no clients remain connected, or the timeout passes.
+func (st *serverStatus) drain(timeout time.Duration) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for st.clients() > 0 {
+		select {
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// storeStatus reports the state of the persistence store, which is
+// disabled unless --store is set.
+func (st *serverStatus) storeStatus() (string, error) {
+	return st.store.status()
+}
+
+type healthReport struct {
+	Status        string   `json:"status"`
+	Version       string   `json:"version"`
+	Uptime        string   `json:"uptime"`
+	UptimeSeconds float64  `json:"uptime_seconds"`
+	Draining      bool     `json:"draining"`
+	Maintenance   bool     `json:"maintenance"`
+	ActiveHubs    int      `json:"active_hubs"`
+	Clients       int      `json:"connected_clients"`
+	Goroutines    int      `json:"goroutines"`
+	Store         string   `json:"store"`
+	Reasons       []string `json:"reasons,omitempty"`
+}
+
+func (st *serverStatus) report(ready bool) healthReport {
+	uptime := time.Since(st.startedAt)
+
+	rep := healthReport{
+		Status:        "ok",
+		Version:       releaseVersion,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		Draining:      st.draining.Load(),
+		Maintenance:   st.maintenance.Load(),
+		Goroutines:    runtime.NumGoroutine(),
+	}
+
+	for _, gm := range st.games {
+		hubs, clients := gm.counts()
+		rep.ActiveHubs += hubs
+		rep.Clients += clients
+	}
+
+	store, err := st.storeStatus()
+	rep.Store = store
+
+	if !ready {
+		return rep
+	}
+
+	if rep.Draining {
+		rep.Reasons = append(rep.Reasons, "draining")
+	}
+	if rep.Maintenance {
+		rep.Reasons = append(rep.Reasons, "maintenance")
+	}
+	if err != nil {
+		rep.Reasons = append(rep.Reasons, "store: "+err.Error())
+	}
+	if len(rep.Reasons) > 0 {
+		rep.Status = "unavailable"
+	}
+
+	return rep
+}
+
+func writeHealth(cfg *Config, w http.ResponseWriter, r *http.Request, rep healthReport) error {
+	status := http.StatusOK
+	if rep.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	securityHeaders(cfg, w)
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+
+		return json.NewEncoder(w).Encode(rep)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+
+	body := "Ok\n"
+	if status != http.StatusOK {
+		body = "Unavailable\n"
+	}
+
+	_, err := w.Write([]byte(body))
+
+	return err
+}
+
+func serveHealthCheck(cfg *Config, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		securityHeaders(cfg, w)
+
+		_, err := w.Write([]byte("Ok\n"))
+		if err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+func serveLiveness(cfg *Config, st *serverStatus, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if err := writeHealth(cfg, w, r, st.report(false)); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+func serveReadiness(cfg *Config, st *serverStatus, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if err := writeHealth(cfg, w, r, st.report(true)); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
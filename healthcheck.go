@@ -0,0 +1,123 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// healthCheckHost maps wildcard bind addresses onto the matching loopback
+// address, since connecting to 0.0.0.0 or :: is not portable.
+func healthCheckHost(bind string) string {
+	switch bind {
+	case "", "0.0.0.0":
+		return "127.0.0.1"
+	case "::", "[::]":
+		return "::1"
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(bind, "["), "]")
+}
+
+// healthCheckLoopback reports whether the probe stays on the local machine,
+// over a unix socket or to a loopback address.
+func healthCheckLoopback(cfg *Config) bool {
+	if _, ok := cfg.socketPath(); ok {
+		return true
+	}
+
+	host := healthCheckHost(cfg.bind)
+	if host == "localhost" {
+		return true
+	}
+
+	a, err := netip.ParseAddr(host)
+
+	return err == nil && a.IsLoopback()
+}
+
+func healthCheckURL(cfg *Config) string {
+	host := "localhost"
+	if _, ok := cfg.socketPath(); !ok {
+		host = net.JoinHostPort(healthCheckHost(cfg.bind), strconv.Itoa(cfg.port))
+	}
+
+	return fmt.Sprintf("%s://%s%s/healthz/live",
+		cfg.scheme(),
+		host,
+		strings.TrimSuffix(cfg.prefix, "/"),
+	)
+}
+
+func runHealthCheck(cfg *Config) error {
+	tlsConfig := &tls.Config{}
+
+	switch {
+	case healthCheckLoopback(cfg):
+		// A loopback address will not match the names in the served
+		// certificate, and cannot be reached from elsewhere.
+		tlsConfig.InsecureSkipVerify = true
+	case cfg.baseURL != nil:
+		// Otherwise, the certificate is expected to be valid for the
+		// public name of the server.
+		tlsConfig.ServerName = cfg.baseURL.Hostname()
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+
+	if path, ok := cfg.socketPath(); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+
+	resp, err := client.Get(healthCheckURL(cfg))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func newHealthCheckCmd(cfg *Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Query the health endpoint of a running instance and exit non-zero on failure.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cfg.validate(); err != nil {
+				return err
+			}
+
+			return runHealthCheck(cfg)
+		},
+	}
+}
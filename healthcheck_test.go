@@ -0,0 +1,33 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "testing"
+
+func TestHealthCheckTarget(t *testing.T) {
+	tests := []struct {
+		bind     string
+		url      string
+		loopback bool
+	}{
+		{"0.0.0.0", "http://127.0.0.1:8080/healthz/live", true},
+		{"::", "http://[::1]:8080/healthz/live", true},
+		{"127.0.0.2", "http://127.0.0.2:8080/healthz/live", true},
+		{"localhost", "http://localhost:8080/healthz/live", true},
+		{"192.0.2.10", "http://192.0.2.10:8080/healthz/live", false},
+		{"[2001:db8::1]", "http://[2001:db8::1]:8080/healthz/live", false},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{bind: tt.bind, port: 8080}
+
+		if got := healthCheckURL(cfg); got != tt.url {
+			t.Errorf("healthCheckURL(%q) = %q, want %q", tt.bind, got, tt.url)
+		}
+		if got := healthCheckLoopback(cfg); got != tt.loopback {
+			t.Errorf("healthCheckLoopback(%q) = %v, want %v", tt.bind, got, tt.loopback)
+		}
+	}
+}
@@ -12,20 +12,6 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func serveHealthCheck(cfg *Config, errs chan<- error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		securityHeaders(cfg, w)
-
-		_, err := w.Write([]byte("Ok\n"))
-		if err != nil {
-			errs <- err
-
-			return
-		}
-	}
-}
-
 func serveRobots(cfg *Config, errs chan<- error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		data := `User-agent: Amazonbot
@@ -73,6 +59,6 @@ func serveHomePage(cfg *Config) httprouter.Handle {
 		securityHeaders(cfg, w)
 
 		// Temporary redirect to celebrity game, until others are added
-		http.Redirect(w, r, cfg.prefix+"/celebrity", http.StatusTemporaryRedirect)
+		http.Redirect(w, r, cfg.redirectURL(cfg.prefix+"/celebrity"), http.StatusTemporaryRedirect)
 	}
 }
@@ -0,0 +1,126 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// inviteParam is the query parameter carrying an invite token, and the
+	// name under which tokens are signed.
+	inviteParam = "invite"
+
+	// displayParam is the query parameter carrying a display's token.
+	displayParam = "display"
+
+	// Kinds of signed links a moderator can create.
+	inviteJoin    = "invite"
+	inviteReclaim = "reclaim"
+	inviteDisplay = "display" // lets a paired display reconnect without pairing again
+
+	// Roles an invite may assign.
+	rolePlayer    = "player"
+	roleSpectator = "spectator"
+)
+
+var (
+	errInvalidInvite = errors.New("This invite link is not valid for this game.")
+	errExpiredInvite = errors.New("This invite link has expired.")
+	errUsedInvite    = errors.New("This invite link has already been used.")
+	errAlreadyJoined = errors.New("This device has already joined this game.")
+)
+
+// invite is the signed payload of an invite or moderator reclaim link.
+// Field names are kept short, since the token is carried in a URL and
+// often encoded as a QR code.
+type invite struct {
+	Kind      string `json:"k"`
+	Game      string `json:"g"` // game type and ID, e.g. celebrity/AbCd1234
+	Created   int64  `json:"c"` // creation time of the game, so that links die with it
+	ID        string `json:"n"`
+	Role      string `json:"r,omitempty"`
+	Team      string `json:"t,omitempty"`
+	Expires   int64  `json:"e,omitempty"` // unix time; zero never expires
+	SingleUse bool   `json:"o,omitempty"`
+}
+
+// inviteGrant records what a redeemed invite entitles a player to.
+type inviteGrant struct {
+	role string
+	team string
+}
+
+func newInviteID() (string, error) {
+	buf := make([]byte, 9)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (inv *invite) expiresAt() time.Time {
+	if inv.Expires == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(inv.Expires, 0)
+}
+
+// encodeInvite signs an invite with the current key, returning a token of
+// the form payload.signature.
+func encodeInvite(key []byte, inv *invite) (string, error) {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + signValue(key, inviteParam, payload), nil
+}
+
+// decodeInvite verifies a token against any of keys and checks that it has
+// not expired.
+func decodeInvite(keys [][]byte, token string, now time.Time) (*invite, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidInvite
+	}
+
+	valid := false
+	for _, key := range keys {
+		if hmac.Equal([]byte(sig), []byte(signValue(key, inviteParam, payload))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errInvalidInvite
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidInvite
+	}
+
+	var inv invite
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, errInvalidInvite
+	}
+
+	if inv.Expires != 0 && !now.Before(inv.expiresAt()) {
+		return nil, errExpiredInvite
+	}
+
+	return &inv, nil
+}
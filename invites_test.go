@@ -0,0 +1,127 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInviteRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	inv := &invite{
+		Kind:      inviteJoin,
+		Game:      "celebrity/AbCd1234",
+		Created:   now.UnixNano(),
+		ID:        "id",
+		Role:      rolePlayer,
+		Team:      "Red",
+		Expires:   now.Add(time.Hour).Unix(),
+		SingleUse: true,
+	}
+
+	token, err := encodeInvite(testCookieKey, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeInvite([][]byte{oldCookieKey, testCookieKey}, token, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *inv {
+		t.Errorf("decodeInvite = %+v, want %+v", got, inv)
+	}
+}
+
+func TestDecodeInvite(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	keys := [][]byte{testCookieKey}
+
+	encode := func(key []byte, inv *invite) string {
+		token, err := encodeInvite(key, inv)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	valid := encode(testCookieKey, &invite{Kind: inviteJoin, Game: "celebrity/AbCd1234"})
+	payload, _, _ := strings.Cut(valid, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"k":"reclaim","g":"celebrity/AbCd1234"}`))
+	garbage := base64.RawURLEncoding.EncodeToString([]byte("not json"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", valid, nil},
+		{"never expires", encode(testCookieKey, &invite{Kind: inviteJoin}), nil},
+		{"not yet expired", encode(testCookieKey, &invite{Kind: inviteJoin, Expires: now.Unix() + 1}), nil},
+		{"expired", encode(testCookieKey, &invite{Kind: inviteJoin, Expires: now.Unix()}), errExpiredInvite},
+		{"unknown key", encode(oldCookieKey, &invite{Kind: inviteJoin}), errInvalidInvite},
+		{"changed payload", forged + valid[len(payload):], errInvalidInvite},
+		{"unsigned", payload, errInvalidInvite},
+		{"empty", "", errInvalidInvite},
+		{"signed for a cookie", payload + "." + signCookie(testCookieKey, payload), errInvalidInvite},
+		{"not base64", "!!!." + signValue(testCookieKey, inviteParam, "!!!"), errInvalidInvite},
+		{"not json", garbage + "." + signValue(testCookieKey, inviteParam, garbage), errInvalidInvite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeInvite(keys, tt.token, now); !errors.Is(err, tt.want) {
+				t.Errorf("decodeInvite = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeedTeams(t *testing.T) {
+	tests := []struct {
+		name      string
+		teams     []string // team of each player, by index
+		eliminate []int
+		seeded    bool
+		remaining int
+	}{
+		{"no teams", []string{"", "", ""}, nil, false, 3},
+		{"no teams, one left", []string{"", "", ""}, []int{1, 2}, false, 1},
+		{"distinct teams", []string{"Red", "Blue", ""}, nil, false, 3},
+		{"shared team", []string{"Red", "Red", ""}, nil, true, 2},
+		{"shared team, one player each left", []string{"Red", "Red", "Blue", "Blue"}, []int{1, 3}, true, 2},
+		{"one team left", []string{"Red", "Red", "Blue"}, []int{2}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHub("celebrity", "test", "")
+			h.teams = make(map[string]string)
+			h.eliminated = make(map[string]bool)
+
+			for i, team := range tt.teams {
+				h.players = append(h.players, Player{PlayerID: string(rune('a' + i)), Team: team})
+			}
+
+			h.seedTeamsLocked()
+			for _, i := range tt.eliminate {
+				h.eliminated[h.players[i].PlayerID] = true
+			}
+
+			if h.teamsSeeded != tt.seeded {
+				t.Errorf("teamsSeeded = %v, want %v", h.teamsSeeded, tt.seeded)
+			}
+			if got := h.remainingLocked(); got != tt.remaining {
+				t.Errorf("remainingLocked = %d, want %d", got, tt.remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxMessageSize bounds a single WebSocket frame from a client, regardless
+// of the configured field lengths.
+const maxMessageSize = 16 << 10
+
+// maxDisplays bounds the paired shared screens attached to one game.
+const maxDisplays = 4
+
+// Displays waiting to be paired are bounded separately. Once the limit is
+// reached, further displays are refused rather than replacing one already
+// waiting, so that nobody can keep changing the code a TV shows; any display
+// not paired in time is disconnected, freeing its place. A paired display
+// can reconnect with its token until it expires.
+const (
+	maxUnpairedDisplays   = 4
+	displayPairingTimeout = 10 * time.Minute
+	displayTokenLifetime  = 24 * time.Hour
+)
+
+// Bounds on the length of a lobby PIN.
+const (
+	minPINLength = 4
+	maxPINLength = 32
+)
+
+var (
+	errTooManyGames       = errors.New("This server has reached its limit of active games. Please try again later.")
+	errTooManyGamesForIP  = errors.New("Too many games are already active from your network. Please try again later.")
+	errGameFull           = errors.New("This game has reached its limit of connections.")
+	errTooManyConnections = errors.New("You are connected to this game from too many tabs or devices.")
+	errTooManyDisplays    = errors.New("This game has reached its limit of displays.")
+	errTooManyUnpaired    = errors.New("Too many displays are waiting to be paired with this game. Please try again later.")
+	errPairingExpired     = errors.New("This pairing code has expired. Reload the page to show a new one.")
+	errNoSuchGame         = errors.New("This game does not exist. Open it on a player's device first.")
+)
+
+// gameQuota tracks active games across all game types, in total and by the
+// address which created them.
+type gameQuota struct {
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+var quota = &gameQuota{
+	perIP: make(map[string]int),
+}
+
+// acquire reserves a game for ip, or reports which limit prevents it.
+func (q *gameQuota) acquire(cfg *Config, ip string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limits := cfg.limits()
+
+	if limits.MaxGames > 0 && q.total >= limits.MaxGames {
+		return errTooManyGames
+	}
+	if limits.MaxGamesPerIP > 0 && q.perIP[ip] >= limits.MaxGamesPerIP {
+		return errTooManyGamesForIP
+	}
+
+	q.total++
+	q.perIP[ip]++
+
+	return nil
+}
+
+func (q *gameQuota) release(ip string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.total--
+	q.perIP[ip]--
+	if q.perIP[ip] <= 0 {
+		delete(q.perIP, ip)
+	}
+}
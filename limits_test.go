@@ -0,0 +1,95 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGameQuota(t *testing.T) {
+	cfg := &Config{maxGames: 3, maxGamesPerIP: 2}
+	q := &gameQuota{perIP: make(map[string]int)}
+
+	steps := []struct {
+		release bool
+		ip      string
+		want    error
+	}{
+		{false, "a", nil},
+		{false, "a", nil},
+		{false, "a", errTooManyGamesForIP},
+		{false, "b", nil},
+		{false, "c", errTooManyGames},
+		{true, "a", nil},
+		{false, "c", nil},
+		{false, "a", errTooManyGames},
+		{true, "b", nil},
+		{false, "a", nil},
+		{false, "a", errTooManyGames},
+		{true, "c", nil},
+		{false, "a", errTooManyGamesForIP},
+	}
+
+	for i, s := range steps {
+		if s.release {
+			q.release(s.ip)
+			continue
+		}
+
+		if err := q.acquire(cfg, s.ip); !errors.Is(err, s.want) {
+			t.Fatalf("step %d: acquire(%q) = %v, want %v", i, s.ip, err, s.want)
+		}
+	}
+
+	if q.total != 2 || q.perIP["a"] != 2 || len(q.perIP) != 1 {
+		t.Errorf("quota = %d %v, want 2 map[a:2]", q.total, q.perIP)
+	}
+}
+
+func TestGameQuotaUnlimited(t *testing.T) {
+	cfg := &Config{}
+	q := &gameQuota{perIP: make(map[string]int)}
+
+	for range 100 {
+		if err := q.acquire(cfg, "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHubAdmit(t *testing.T) {
+	tests := []struct {
+		name      string
+		perPlayer int
+		total     int
+		connected []string // player IDs already connected
+		player    string
+		want      error
+	}{
+		{"unlimited", 0, 0, []string{"host", "a", "a", "b"}, "a", nil},
+		{"per player", 2, 0, []string{"host", "a", "a"}, "a", errTooManyConnections},
+		{"per player other", 2, 0, []string{"host", "a", "a"}, "b", nil},
+		{"game full", 0, 3, []string{"host", "a", "b"}, "c", errGameFull},
+		{"game full host", 0, 3, []string{"host", "a", "b"}, "host", nil},
+		{"host per player", 1, 3, []string{"host", "a", "b"}, "host", errTooManyConnections},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{maxConnectionsPerPlayer: tt.perPlayer, maxConnections: tt.total}
+
+			h := newHub("celebrity", "test", "")
+			h.moderatorPlayerID = "host"
+			for _, id := range tt.connected {
+				h.clients[&Client{playerID: id}] = true
+			}
+
+			if err := h.admitLocked(cfg, &Client{playerID: tt.player}); !errors.Is(err, tt.want) {
+				t.Errorf("admitLocked(%q) = %v, want %v", tt.player, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	unixPrefix = "unix:"
+
+	// systemdFirstFD is SD_LISTEN_FDS_START, the first descriptor passed by
+	// systemd socket activation.
+	systemdFirstFD = 3
+)
+
+// socketPath returns the path of the unix domain socket to listen on, if
+// --bind is of the form unix:/path/to/socket.
+func (c *Config) socketPath() (string, bool) {
+	if !strings.HasPrefix(c.bind, unixPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(c.bind, unixPrefix), true
+}
+
+func parseSocketMode(mode string) (fs.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("invalid socket mode (must be octal, e.g. 0660): %q", mode)
+	}
+
+	return fs.FileMode(m), nil
+}
+
+// systemdListeners returns any listening sockets passed in by systemd
+// socket activation, as described in sd_listen_fds(3).
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// Prevent the descriptors from being inherited by any child processes.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, n)
+
+	for i := range n {
+		name := "LISTEN_FD_" + strconv.Itoa(systemdFirstFD+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(systemdFirstFD+i), name)
+
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to use socket %q from systemd: %w", name, err)
+		}
+
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// listenUnix listens on a unix domain socket, replacing any stale socket
+// file left behind by a previous instance.
+func listenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("refusing to replace non-socket file %q", path)
+		}
+
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("socket %q is already in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// listen opens the main listener, preferring a socket passed in by systemd,
+// then a unix domain socket, and finally TCP. The returned address is only
+// used for logging.
+func listen(cfg *Config) (net.Listener, string, error) {
+	listeners, err := systemdListeners()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(listeners) > 0 {
+		for _, extra := range listeners[1:] {
+			slog.Warn("Ignoring additional socket from systemd", "addr", extra.Addr().String())
+			extra.Close()
+		}
+
+		return listeners[0], listeners[0].Addr().String(), nil
+	}
+
+	if path, ok := cfg.socketPath(); ok {
+		mode, err := parseSocketMode(cfg.socketMode)
+		if err != nil {
+			return nil, "", err
+		}
+
+		ln, err := listenUnix(path, mode)
+
+		return ln, path, err
+	}
+
+	addr := net.JoinHostPort(cfg.bind, strconv.Itoa(cfg.port))
+
+	ln, err := net.Listen("tcp", addr)
+
+	return ln, addr, err
+}
@@ -5,7 +5,11 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -17,5 +21,9 @@ const (
 func main() {
 	log.SetFlags(0)
 	cfg := &Config{}
-	cobra.CheckErr(newCmd(cfg).Execute())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cobra.CheckErr(newCmd(cfg).ExecuteContext(ctx))
 }
@@ -0,0 +1,316 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	durationBuckets = []float64{30, 60, 120, 300, 600, 900, 1200, 1800, 2700, 3600}
+	latencyBuckets  = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// counterVec is a set of monotonically increasing values, keyed by label values.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+func (c *counterVec) inc(values ...string) {
+	c.add(1, values...)
+}
+
+func (c *counterVec) add(n float64, values ...string) {
+	key := strings.Join(values, "\xff")
+
+	c.mu.Lock()
+	c.values[key] += n
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, splitKey(key), "", ""), formatValue(c.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// histogramVec tracks observations in cumulative buckets, keyed by label values.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+
+	for i, b := range h.buckets {
+		if v <= b {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		values := splitKey(key)
+
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, values, "le", formatValue(b)), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, values, "le", "+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, values, "", ""), formatValue(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, values, "", ""), hist.count)
+	}
+}
+
+type Metrics struct {
+	gamesCreated     *counterVec
+	gamesReaped      *counterVec
+	messagesIn       *counterVec
+	messagesOut      *counterVec
+	droppedClients   *counterVec
+	rejectedOrigins  *counterVec
+	bannedClients    *counterVec
+	guesses          *counterVec
+	filtered         *counterVec
+	rateLimited      *counterVec
+	gameDuration     *histogramVec
+	requestDurations *histogramVec
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		gamesCreated:     newCounterVec("partybox_games_created_total", "Games created.", "game"),
+		gamesReaped:      newCounterVec("partybox_games_reaped_total", "Games removed after the idle timeout.", "game"),
+		messagesIn:       newCounterVec("partybox_websocket_messages_received_total", "WebSocket messages received from clients.", "game", "type"),
+		messagesOut:      newCounterVec("partybox_websocket_messages_sent_total", "WebSocket messages sent to clients.", "game", "type"),
+		droppedClients:   newCounterVec("partybox_websocket_clients_dropped_total", "Clients disconnected because their send buffer was full.", "game"),
+		rejectedOrigins:  newCounterVec("partybox_websocket_origin_rejections_total", "WebSocket upgrades rejected because of their origin.", "game"),
+		bannedClients:    newCounterVec("partybox_banned_connections_total", "Connections and game creations refused by a ban, by scope.", "scope"),
+		guesses:          newCounterVec("partybox_guesses_total", "Guesses made, by outcome.", "game", "result"),
+		filtered:         newCounterVec("partybox_content_filter_matches_total", "User-supplied text matched by the content filter, by field and action.", "game", "field", "action"),
+		rateLimited:      newCounterVec("partybox_rate_limited_total", "Requests and commands rejected by rate limits, by limit.", "limit"),
+		gameDuration:     newHistogramVec("partybox_game_duration_seconds", "Time from game start until a winner is decided.", durationBuckets, "game"),
+		requestDurations: newHistogramVec("partybox_http_request_duration_seconds", "HTTP request latencies, by route.", latencyBuckets, "method", "route", "code"),
+	}
+}
+
+var metrics = newMetrics()
+
+func (m *Metrics) write(w *bufio.Writer, st *serverStatus) {
+	writeHeader(w, "partybox_games_active", "Games currently in memory.", "gauge")
+	for _, gm := range st.games {
+		hubs, _ := gm.counts()
+		fmt.Fprintf(w, "partybox_games_active%s %d\n", formatLabels([]string{"game"}, []string{gm.name}, "", ""), hubs)
+	}
+
+	writeHeader(w, "partybox_websocket_clients", "WebSocket clients currently connected.", "gauge")
+	for _, gm := range st.games {
+		_, clients := gm.counts()
+		fmt.Fprintf(w, "partybox_websocket_clients%s %d\n", formatLabels([]string{"game"}, []string{gm.name}, "", ""), clients)
+	}
+
+	m.gamesCreated.write(w)
+	m.gamesReaped.write(w)
+	m.messagesIn.write(w)
+	m.messagesOut.write(w)
+	m.droppedClients.write(w)
+	m.rejectedOrigins.write(w)
+	m.bannedClients.write(w)
+	m.guesses.write(w)
+	m.filtered.write(w)
+	m.rateLimited.write(w)
+	m.gameDuration.write(w)
+	m.requestDurations.write(w)
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var b strings.Builder
+
+	for i, name := range names {
+		if i < len(values) {
+			if b.Len() > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(name + "=" + quoteLabel(values[i]))
+		}
+	}
+
+	if extraName != "" {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName + "=" + quoteLabel(extraValue))
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+
+	return "{" + b.String() + "}"
+}
+
+// labelEscaper escapes label values as required by the Prometheus text
+// format, which unlike Go only escapes backslashes, quotes and newlines.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+func splitKey(key string) []string {
+	return strings.Split(key, "\xff")
+}
+
+// messageType returns the type of an outgoing WebSocket message.
+func messageType(msg outgoingMessage) string {
+	if t := msg.MessageType(); t != "" {
+		return t
+	}
+
+	return "unknown"
+}
+
+// routePattern reconstructs the registered route for a request, so that
+// request metrics are not labelled with unbounded game IDs.
+func routePattern(mux *httprouter.Router, r *http.Request) string {
+	handle, ps, _ := mux.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return "unmatched"
+	}
+
+	route := r.URL.Path
+	for _, p := range ps {
+		if strings.HasPrefix(p.Value, "/") && strings.HasSuffix(route, p.Value) {
+			route = strings.TrimSuffix(route, p.Value) + "/*" + p.Key
+			continue
+		}
+		route = strings.Replace(route, "/"+p.Value, "/:"+p.Key, 1)
+	}
+
+	return route
+}
+
+func instrumentHandler(mux *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		switch status {
+		case 0:
+			status = http.StatusOK
+		case http.StatusSwitchingProtocols:
+			// WebSocket sessions are tracked separately, and would otherwise
+			// skew latencies by the length of the session.
+			return
+		}
+
+		metrics.requestDurations.observe(time.Since(startTime).Seconds(), r.Method, routePattern(mux, r), strconv.Itoa(status))
+	})
+}
+
+func serveMetrics(cfg *Config, st *serverStatus, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		securityHeaders(cfg, w)
+
+		bw := bufio.NewWriter(w)
+		metrics.write(bw, st)
+
+		if err := bw.Flush(); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "testing"
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		names, values []string
+		extra, value  string
+		want          string
+	}{
+		{nil, nil, "", "", ""},
+		{[]string{"game"}, []string{"celebrity"}, "", "", `{game="celebrity"}`},
+		{[]string{"game", "type"}, []string{"celebrity", "join"}, "le", "0.5", `{game="celebrity",type="join",le="0.5"}`},
+		{[]string{"route"}, []string{`a"b`}, "", "", `{route="a\"b"}`},
+		{[]string{"route"}, []string{`a\b`}, "", "", `{route="a\\b"}`},
+		{[]string{"route"}, []string{"a\nb"}, "", "", `{route="a\nb"}`},
+		{[]string{"route"}, []string{"café\t\x01"}, "", "", "{route=\"café\t\x01\"}"},
+	}
+
+	for _, tt := range tests {
+		if got := formatLabels(tt.names, tt.values, tt.extra, tt.value); got != tt.want {
+			t.Errorf("formatLabels(%q, %q) = %s, want %s", tt.names, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestMessageType(t *testing.T) {
+	tests := []struct {
+		msg  outgoingMessage
+		want string
+	}{
+		{SimpleMessage{Type: "error"}, "error"},
+		{&GameStateMessage{Type: "game_state"}, "game_state"},
+		{SimpleMessage{}, "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := messageType(tt.msg); got != tt.want {
+			t.Errorf("messageType(%#v) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed names/confusables.txt
+var confusablesData string
+
+// confusables maps characters to their prototypes, as used to compute
+// skeletons.
+var confusables = parseConfusables(confusablesData)
+
+func parseConfusables(data string) map[rune]string {
+	table := make(map[rune]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+
+		source := []rune(parseCodepoints(fields[0]))
+		if len(source) != 1 {
+			continue
+		}
+
+		table[source[0]] = parseCodepoints(fields[1])
+	}
+
+	return table
+}
+
+func parseCodepoints(s string) string {
+	var b strings.Builder
+
+	for _, hex := range strings.Fields(s) {
+		r, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return ""
+		}
+		b.WriteRune(rune(r))
+	}
+
+	return b.String()
+}
+
+// skeleton returns the skeleton of s as defined by Unicode Technical
+// Standard #39, which is equal for strings that are visually confusable.
+func skeleton(s string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFD.String(s) {
+		if target, ok := confusables[r]; ok {
+			b.WriteString(target)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return norm.NFD.String(b.String())
+}
+
+// normalizeName applies NFKC normalization, drops invisible formatting
+// characters, and trims and collapses whitespace.
+func normalizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Variation_Selector, r) {
+			return -1
+		}
+
+		return r
+	}, norm.NFKC.String(s))
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// nameKey holds the forms of a name compared to detect collisions, so that
+// names differing only in case, normalization, spacing, or confusable
+// characters are treated as the same.
+type nameKey struct {
+	folded string
+
+	// Skeletons are computed both before and after case folding, since
+	// some confusables, such as I and l, only look alike in a particular
+	// case.
+	skeleton       string
+	foldedSkeleton string
+}
+
+func newNameKey(s string) nameKey {
+	normalized := normalizeName(s)
+	folded := cases.Fold().String(normalized)
+
+	return nameKey{
+		folded:         folded,
+		skeleton:       cases.Fold().String(skeleton(normalized)),
+		foldedSkeleton: cases.Fold().String(skeleton(folded)),
+	}
+}
+
+func (k nameKey) empty() bool {
+	return k.folded == ""
+}
+
+func (k nameKey) collides(other nameKey) bool {
+	return k.folded == other.folded ||
+		k.skeleton == other.skeleton ||
+		k.foldedSkeleton == other.foldedSkeleton
+}
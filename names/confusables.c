@@ -0,0 +1,109 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+// Writes the confusables table built into ICU in the format of
+// confusables.txt from Unicode Technical Standard #39, for use when the
+// original file is not at hand.
+//
+//   cc -o confusables confusables.c $(pkg-config --libs icu-uc icu-i18n)
+//   ./confusables > confusables.txt
+
+#include <stdio.h>
+#include <string.h>
+#include <unicode/uchar.h>
+#include <unicode/unorm2.h>
+#include <unicode/uspoof.h>
+#include <unicode/ustring.h>
+
+static void print_codepoints(const UChar *s, int32_t len) {
+	for (int32_t i = 0; i < len;) {
+		UChar32 c;
+		U16_NEXT(s, i, len, c);
+		printf(i < len ? "%04X " : "%04X", c);
+	}
+}
+
+static void print_names(const UChar *s, int32_t len) {
+	for (int32_t i = 0; i < len;) {
+		UChar32 c;
+		char name[256];
+		UErrorCode err = U_ZERO_ERROR;
+		U16_NEXT(s, i, len, c);
+		u_charName(c, U_UNICODE_CHAR_NAME, name, sizeof name, &err);
+		printf(i < len ? "%s, " : "%s", name);
+	}
+}
+
+static void print_string(const UChar *s, int32_t len) {
+	char out[256];
+	UErrorCode err = U_ZERO_ERROR;
+	u_strToUTF8(out, sizeof out, NULL, s, len, &err);
+	printf("%s", out);
+}
+
+int main(void) {
+	UErrorCode err = U_ZERO_ERROR;
+	USpoofChecker *sc = uspoof_open(&err);
+	const UNormalizer2 *nfd = unorm2_getNFDInstance(&err);
+	if (U_FAILURE(err)) {
+		fprintf(stderr, "%s\n", u_errorName(err));
+		return 1;
+	}
+
+	UVersionInfo version;
+	char unicode[U_MAX_VERSION_STRING_LENGTH];
+	u_getUnicodeVersion(version);
+	u_versionToString(version, unicode);
+
+	printf("# Confusable characters, in the format of confusables.txt from Unicode\n");
+	printf("# Technical Standard #39 (https://www.unicode.org/Public/security/latest/),\n");
+	printf("# as built into ICU %s for Unicode %s.\n", U_ICU_VERSION, unicode);
+	printf("#\n");
+	printf("# Only characters which are unchanged by NFD are listed, as skeletons are\n");
+	printf("# computed after NFD normalization, and prototypes are given in NFD.\n");
+	printf("#\n");
+	printf("# Generated by confusables.c; the file published by Unicode can be dropped\n");
+	printf("# in place of this one, unchanged.\n");
+	printf("#\n");
+	printf("# Each line maps a source character to its prototype:\n");
+	printf("#   source ; prototype ; type # ( source → prototype ) names\n\n");
+
+	for (UChar32 c = 0; c <= 0x10FFFF; c++) {
+		if (U_IS_SURROGATE(c) || u_charType(c) == U_UNASSIGNED) {
+			continue;
+		}
+
+		UChar src[2], norm[64], skel[64];
+		int32_t srclen = 0;
+		U16_APPEND_UNSAFE(src, srclen, c);
+
+		err = U_ZERO_ERROR;
+		int32_t normlen = unorm2_normalize(nfd, src, srclen, norm, 64, &err);
+		if (U_FAILURE(err) || normlen != srclen || memcmp(norm, src, srclen * sizeof(UChar)) != 0) {
+			continue;
+		}
+
+		err = U_ZERO_ERROR;
+		int32_t skellen = uspoof_getSkeleton(sc, 0, src, srclen, skel, 64, &err);
+		if (U_FAILURE(err) || (skellen == srclen && memcmp(skel, src, srclen * sizeof(UChar)) == 0)) {
+			continue;
+		}
+
+		print_codepoints(src, srclen);
+		printf(" ;\t");
+		print_codepoints(skel, skellen);
+		printf(" ;\tMA\t# ( ");
+		print_string(src, srclen);
+		printf(" → ");
+		print_string(skel, skellen);
+		printf(" ) ");
+		print_names(src, srclen);
+		printf(" → ");
+		print_names(skel, skellen);
+		printf("\n");
+	}
+
+	uspoof_close(sc);
+	return 0;
+}
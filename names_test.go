@@ -0,0 +1,95 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "testing"
+
+func TestNameKeyCollides(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		collide bool
+	}{
+		{"Alice", "Alice", true},
+		{"Alice", "alice", true},
+		{"Alice", " ALICE ", true},
+		{"Mary Jane", "Mary   Jane", true},
+		{"Ａｌｉｃｅ", "Alice", true},
+		{"Al\u200bice", "Alice", true},
+		{"Jos\u00e9", "Jose\u0301", true},
+		{"\u0410lice", "Alice", true},
+		{"AIice", "Alice", true},
+		{"A1ice", "Alice", true},
+		{"rnary", "mary", true},
+		{"\u13aalice", "Alice", true},
+		{"Alice", "Alicia", false},
+		{"Bob", "Rob", false},
+		{"Jose", "Jos\u00e9", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			a, b := newNameKey(tt.a), newNameKey(tt.b)
+
+			if got := a.collides(b); got != tt.collide {
+				t.Errorf("%q collides with %q = %v, want %v", tt.a, tt.b, got, tt.collide)
+			}
+			if got := b.collides(a); got != tt.collide {
+				t.Errorf("%q collides with %q = %v, want %v", tt.b, tt.a, got, tt.collide)
+			}
+		})
+	}
+}
+
+func TestNameKeyEmpty(t *testing.T) {
+	for _, s := range []string{"", "   ", "\u200b", "\ufe0f"} {
+		if !newNameKey(s).empty() {
+			t.Errorf("newNameKey(%q) is not empty", s)
+		}
+	}
+
+	if newNameKey("a").empty() {
+		t.Error(`newNameKey("a") is empty`)
+	}
+}
+
+func TestParseConfusables(t *testing.T) {
+	table := parseConfusables("\ufeff# header\n0030 ;\t004F ;\tMA\t# ( 0 → O )\n006D ;\t0072 006E ;\tMA\nzz ; 0041 ; MA\n0072 006E ; 006D ; MA\n")
+
+	if table['0'] != "O" || table['m'] != "rn" {
+		t.Errorf("table = %q", table)
+	}
+	if len(table) != 2 {
+		t.Errorf("parsed %d entries, want 2", len(table))
+	}
+}
+
+// TestSkeleton checks lookalikes both across and within scripts.
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"paypal", "p\u0430yp\u0430l"},
+		{"scope", "\u0455\u0441\u043e\u0440\u0435"},
+		{"sparkasse", "\u0455\u0440\u0430rk\u0430\u0455\u0455\u0435"},
+		{"\u0398", "\u04e8"},
+		{"\u0398", "\u0472"},
+		{"rn", "m"},
+		{"\u2116", "No"},
+		{"\u05d5\u05d5", "\u05f0"},
+		{"\u0644\u0627", "\ufefb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if a, b := skeleton(tt.a), skeleton(tt.b); a != b {
+				t.Errorf("skeleton(%q) = %q, skeleton(%q) = %q", tt.a, a, tt.b, b)
+			}
+		})
+	}
+
+	if skeleton("Alice") == skeleton("Alicia") {
+		t.Error(`skeleton("Alice") = skeleton("Alicia")`)
+	}
+}
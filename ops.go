@@ -0,0 +1,292 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// listenAddr listens on a host:port, or on a unix socket if addr is of the
+// form unix:/path/to/socket.
+func listenAddr(cfg *Config, addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		mode, err := parseSocketMode(cfg.socketMode)
+		if err != nil {
+			return nil, err
+		}
+
+		return listenUnix(path, mode)
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// serve accepts connections on ln in the background until srv is shut down.
+func serve(srv *http.Server, ln net.Listener, name string) {
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Listener failed", "listener", name, "error", err)
+		}
+	}()
+}
+
+func validListenAddr(addr string) bool {
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		return path != ""
+	}
+
+	_, _, err := net.SplitHostPort(addr)
+
+	return err == nil
+}
+
+func writeJSON(cfg *Config, w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	securityHeaders(cfg, w)
+	w.WriteHeader(status)
+
+	return json.NewEncoder(w).Encode(v)
+}
+
+func serveAdminStatus(cfg *Config, st *serverStatus, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if err := writeJSON(cfg, w, http.StatusOK, st.report(true)); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+func serveAdminMaintenance(cfg *Config, st *serverStatus, enabled bool, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if st.maintenance.Swap(enabled) != enabled {
+			slog.Warn("Maintenance mode changed", "enabled", enabled, "ip", realIP(r))
+		}
+
+		if err := writeJSON(cfg, w, http.StatusOK, map[string]bool{"maintenance": enabled}); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+func serveAdminListBans(cfg *Config, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if err := writeJSON(cfg, w, http.StatusOK, bans.list()); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+// banRequest is the body accepted by POST /admin/bans. Duration is a Go
+// duration string, and the ban never expires if it is omitted.
+type banRequest struct {
+	Game     string `json:"game"`
+	PlayerID string `json:"player_id"`
+	IP       string `json:"ip"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+func serveAdminAddBan(cfg *Config, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		var req banRequest
+
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxMessageSize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			_ = writeJSON(cfg, w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+
+			return
+		}
+
+		b := Ban{
+			Game:     req.Game,
+			PlayerID: req.PlayerID,
+			IP:       req.IP,
+			Reason:   req.Reason,
+			Source:   "admin",
+		}
+
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				_ = writeJSON(cfg, w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid duration (must be positive, e.g. 24h): %q", req.Duration)})
+
+				return
+			}
+			b.ExpiresAt = time.Now().Add(d)
+		}
+
+		ban, err := newBan(b)
+		if err != nil {
+			_ = writeJSON(cfg, w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+
+			return
+		}
+
+		if err := bans.add(ban); err != nil {
+			slog.Error("Failed to save bans", "error", err)
+		}
+
+		slog.Warn("Ban added", "id", ban.ID, "game", ban.Game, "player_id", ban.PlayerID, "ban_ip", ban.IP, "expires_at", ban.ExpiresAt, "ip", realIP(r))
+
+		if err := writeJSON(cfg, w, http.StatusCreated, ban); err != nil {
+			errs <- err
+
+			return
+		}
+	}
+}
+
+func serveAdminRemoveBan(cfg *Config, errs chan<- error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		id := p.ByName("id")
+
+		found, err := bans.remove(id)
+		if err != nil {
+			slog.Error("Failed to save bans", "error", err)
+		}
+
+		if !found {
+			_ = writeJSON(cfg, w, http.StatusNotFound, map[string]string{"error": "no such ban, or it was set in the config file"})
+
+			return
+		}
+
+		slog.Warn("Ban removed", "id", id, "ip", realIP(r))
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+const minAdminTokenLength = 16
+
+// requireAdminToken rejects requests which do not present --admin-token as
+// a bearer token, if one is set.
+func requireAdminToken(cfg *Config, next httprouter.Handle) httprouter.Handle {
+	if cfg.adminToken == "" {
+		return next
+	}
+
+	want := []byte("Bearer " + cfg.adminToken)
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			slog.Warn("Rejected admin request without valid token", "ip", realIP(r), "path", r.URL.Path)
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="partybox"`)
+			_ = writeJSON(cfg, w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid admin token"})
+
+			return
+		}
+
+		next(w, r, p)
+	}
+}
+
+// registerAdminHandlers mounts the admin API, unless it would be left
+// unauthenticated because neither --admin-token nor --tls-client-ca is set.
+func registerAdminHandlers(cfg *Config, st *serverStatus, mux *httprouter.Router, errs chan<- error) {
+	if cfg.adminToken == "" && cfg.tlsClientCA == "" {
+		slog.Warn("Admin API disabled; set --admin-token or --tls-client-ca to enable it")
+
+		return
+	}
+
+	admin := func(h httprouter.Handle) httprouter.Handle {
+		return requireAdminToken(cfg, h)
+	}
+
+	mux.GET("/admin/status", admin(serveAdminStatus(cfg, st, errs)))
+	mux.POST("/admin/maintenance", admin(serveAdminMaintenance(cfg, st, true, errs)))
+	mux.DELETE("/admin/maintenance", admin(serveAdminMaintenance(cfg, st, false, errs)))
+	mux.GET("/admin/bans", admin(serveAdminListBans(cfg, errs)))
+	mux.POST("/admin/bans", admin(serveAdminAddBan(cfg, errs)))
+	mux.DELETE("/admin/bans/:id", admin(serveAdminRemoveBan(cfg, errs)))
+}
+
+// newOpsServer returns the server for the private operations listener,
+// which exposes metrics, profiling, and the admin API. It shares the TLS
+// configuration of the main listener, so that client certificates can be
+// required with --tls-client-ca.
+func newOpsServer(cfg *Config, st *serverStatus, errs chan<- error) *http.Server {
+	mux := httprouter.New()
+
+	registerAdminHandlers(cfg, st, mux, errs)
+
+	if cfg.metrics {
+		mux.GET("/metrics", serveMetrics(cfg, st, errs))
+	}
+
+	if cfg.profile {
+		registerProfileHandlers(mux)
+	}
+
+	return &http.Server{
+		Handler:           requireClientCert(cfg, mux),
+		ReadTimeout:       timeout,
+		ReadHeaderTimeout: timeout,
+		// CPU profiles and execution traces are collected for up to 30s
+		// by default.
+		WriteTimeout: time.Minute,
+	}
+}
+
+// newRedirectServer returns a plain HTTP server which sends every request to
+// the same path on the main HTTPS listener.
+func newRedirectServer(cfg *Config) *http.Server {
+	return &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			securityHeaders(cfg, w)
+
+			if cfg.baseURL != nil && cfg.baseURL.Scheme == "https" {
+				http.Redirect(w, r, cfg.baseURL.String()+r.URL.RequestURI(), http.StatusPermanentRedirect)
+
+				return
+			}
+
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+
+			if _, ok := cfg.socketPath(); !ok && cfg.port != 443 {
+				host += ":" + strconv.Itoa(cfg.port)
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+		ReadTimeout:       timeout,
+		ReadHeaderTimeout: timeout,
+		WriteTimeout:      timeout,
+	}
+}
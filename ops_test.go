@@ -0,0 +1,49 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAdminAuth(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{"disabled", "", "Bearer " + token, http.StatusNotFound},
+		{"missing", token, "", http.StatusUnauthorized},
+		{"wrong", token, "Bearer fedcba9876543210", http.StatusUnauthorized},
+		{"not bearer", token, token, http.StatusUnauthorized},
+		{"valid", token, "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{adminToken: tt.token}
+			mux := httprouter.New()
+			registerAdminHandlers(cfg, newServerStatus(cfg), mux, make(chan error, 1))
+
+			r := httptest.NewRequest("GET", "/admin/status", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
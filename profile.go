@@ -5,20 +5,23 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"net/http"
 	"net/http/pprof"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-func registerProfileHandlers(cfg *Config, mux *httprouter.Router) {
-	mux.Handler("GET", cfg.prefix+"/pprof/allocs", pprof.Handler("allocs"))
-	mux.Handler("GET", cfg.prefix+"/pprof/block", pprof.Handler("block"))
-	mux.Handler("GET", cfg.prefix+"/pprof/goroutine", pprof.Handler("goroutine"))
-	mux.Handler("GET", cfg.prefix+"/pprof/heap", pprof.Handler("heap"))
-	mux.Handler("GET", cfg.prefix+"/pprof/mutex", pprof.Handler("mutex"))
-	mux.Handler("GET", cfg.prefix+"/pprof/threadcreate", pprof.Handler("threadcreate"))
-	mux.HandlerFunc("GET", cfg.prefix+"/pprof/cmdline", pprof.Cmdline)
-	mux.HandlerFunc("GET", cfg.prefix+"/pprof/profile", pprof.Profile)
-	mux.HandlerFunc("GET", cfg.prefix+"/pprof/symbol", pprof.Symbol)
-	mux.HandlerFunc("GET", cfg.prefix+"/pprof/trace", pprof.Trace)
+// registerProfileHandlers registers the net/http/pprof handlers. They are
+// only ever served on the private operations listener.
+func registerProfileHandlers(mux *httprouter.Router) {
+	mux.Handler("GET", "/pprof/allocs", pprof.Handler("allocs"))
+	mux.Handler("GET", "/pprof/block", pprof.Handler("block"))
+	mux.Handler("GET", "/pprof/goroutine", pprof.Handler("goroutine"))
+	mux.Handler("GET", "/pprof/heap", pprof.Handler("heap"))
+	mux.Handler("GET", "/pprof/mutex", pprof.Handler("mutex"))
+	mux.Handler("GET", "/pprof/threadcreate", pprof.Handler("threadcreate"))
+	mux.Handler("GET", "/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	mux.Handler("GET", "/pprof/profile", http.HandlerFunc(pprof.Profile))
+	mux.Handler("GET", "/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	mux.Handler("GET", "/pprof/trace", http.HandlerFunc(pprof.Trace))
 }
@@ -0,0 +1,298 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+type clientContextKey struct{}
+
+// client describes the originating client of a request, as resolved from
+// the connection and any forwarded headers set by a trusted proxy.
+type client struct {
+	addr   netip.Addr
+	port   string
+	scheme string
+}
+
+func (c client) String() string {
+	if !c.addr.IsValid() {
+		return ""
+	}
+
+	host := c.addr.String()
+	if c.addr.Is6() {
+		host = "[" + host + "]"
+	}
+
+	if c.port != "" {
+		return host + ":" + c.port
+	}
+
+	return host
+}
+
+// parseTrustedProxies parses a list of CIDR ranges or single addresses.
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if p, err := netip.ParsePrefix(s); err == nil {
+			prefixes = append(prefixes, p.Masked())
+
+			continue
+		}
+
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy (must be an ip address or cidr range): %q", s)
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func trusted(proxies []netip.Prefix, a netip.Addr) bool {
+	a = a.Unmap()
+
+	for _, p := range proxies {
+		if p.Contains(a) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseNodeAddr parses an address as found in X-Forwarded-For or the for=
+// parameter of a Forwarded header, with or without a port.
+func parseNodeAddr(s string) (netip.Addr, string, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), fmt.Sprint(ap.Port()), true
+	}
+
+	if a, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")); err == nil {
+		return a.Unmap(), "", true
+	}
+
+	return netip.Addr{}, "", false
+}
+
+type forwardedElement struct {
+	addr  netip.Addr
+	port  string
+	valid bool
+	proto string
+}
+
+// parseForwarded parses all Forwarded headers on a request, as described in
+// RFC 7239, in the order in which the proxies appended them.
+func parseForwarded(h http.Header) []forwardedElement {
+	var elements []forwardedElement
+
+	for _, line := range h.Values("Forwarded") {
+		for elem := range strings.SplitSeq(line, ",") {
+			var fe forwardedElement
+
+			for pair := range strings.SplitSeq(elem, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+
+				switch strings.ToLower(key) {
+				case "for":
+					fe.addr, fe.port, fe.valid = parseNodeAddr(value)
+				case "proto":
+					fe.proto = strings.ToLower(strings.Trim(value, `"`))
+				}
+			}
+
+			elements = append(elements, fe)
+		}
+	}
+
+	return elements
+}
+
+func headerList(h http.Header, name string) []string {
+	var list []string
+
+	for _, line := range h.Values(name) {
+		for v := range strings.SplitSeq(line, ",") {
+			list = append(list, strings.TrimSpace(v))
+		}
+	}
+
+	return list
+}
+
+// resolveClient determines the originating client of a request. Forwarded
+// headers are only honored when the connecting peer is a trusted proxy, and
+// are walked from right to left, skipping any further trusted proxies, so
+// that addresses prepended by the client itself are ignored.
+func resolveClient(proxies []netip.Prefix, r *http.Request) client {
+	c := client{scheme: "http"}
+	if r.TLS != nil {
+		c.scheme = "https"
+	}
+
+	host, port, err := net.SplitHostPort(r.RemoteAddr)
+	if err == nil {
+		c.addr, _ = netip.ParseAddr(host)
+		c.addr = c.addr.Unmap()
+		c.port = port
+	}
+
+	if !peerTrusted(proxies, r, c.addr) {
+		return c
+	}
+
+	if elements := parseForwarded(r.Header); len(elements) > 0 {
+		for i := len(elements) - 1; i >= 0; i-- {
+			e := elements[i]
+			if !e.valid {
+				break
+			}
+
+			c.addr, c.port = e.addr, e.port
+			if e.proto == "http" || e.proto == "https" {
+				c.scheme = e.proto
+			}
+
+			if !trusted(proxies, e.addr) {
+				break
+			}
+		}
+
+		return c
+	}
+
+	// depth counts the X-Forwarded-For entries, from the right, up to and
+	// including the one the client was resolved from.
+	depth := 0
+
+	if hops := headerList(r.Header, "X-Forwarded-For"); len(hops) > 0 {
+		for i := len(hops) - 1; i >= 0; i-- {
+			a, _, ok := parseNodeAddr(hops[i])
+			if !ok {
+				break
+			}
+
+			c.addr, c.port = a, ""
+			depth = len(hops) - i
+
+			if !trusted(proxies, a) {
+				break
+			}
+		}
+	} else if a, _, ok := parseNodeAddr(r.Header.Get("X-Real-IP")); ok {
+		c.addr, c.port = a, ""
+	} else if a, _, ok := parseNodeAddr(r.Header.Get("CF-Connecting-IP")); ok {
+		c.addr, c.port = a, ""
+	}
+
+	// Each proxy appends to X-Forwarded-Proto alongside X-Forwarded-For, so
+	// the scheme is taken from the same hop as the address. Entries to the
+	// left of it may have been sent by the client, so if the lists do not
+	// line up, only the rightmost entry, set by the trusted peer, is used.
+	if protos := headerList(r.Header, "X-Forwarded-Proto"); len(protos) > 0 {
+		i := len(protos) - 1
+		if depth > 0 && depth <= len(protos) {
+			i = len(protos) - depth
+		}
+
+		if p := strings.ToLower(protos[i]); p == "http" || p == "https" {
+			c.scheme = p
+		}
+	}
+
+	return c
+}
+
+// peerTrusted reports whether the forwarded headers of a request can be
+// believed. Only local processes can connect over a unix socket, so its peer
+// is always a trusted reverse proxy.
+func peerTrusted(proxies []netip.Prefix, r *http.Request, peer netip.Addr) bool {
+	return r.RemoteAddr == "" || r.RemoteAddr == "@" || (peer.IsValid() && trusted(proxies, peer))
+}
+
+var forwardedHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP", "CF-Connecting-IP"}
+
+var untrustedProxyWarning sync.Once
+
+// warnUntrustedProxy logs, once, that a request arrived with forwarded
+// headers from a peer which is not a trusted proxy. Behind such a proxy,
+// every client appears to have its address, and so shares its per-address
+// limits and bans.
+func warnUntrustedProxy(proxies []netip.Prefix, r *http.Request, peer client) {
+	if peerTrusted(proxies, r, peer.addr) {
+		return
+	}
+
+	for _, name := range forwardedHeaders {
+		if r.Header.Get(name) != "" {
+			untrustedProxyWarning.Do(func() {
+				slog.Warn("Ignoring forwarded headers from an untrusted peer; if it is a reverse proxy, add it to --trusted-proxies, or all clients will share its address and rate limits",
+					"peer", peer.addr.String(), "header", name)
+			})
+
+			return
+		}
+	}
+}
+
+// clientHandler resolves the client of each request, for use by realIP and
+// clientScheme.
+func clientHandler(cfg *Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := resolveClient(cfg.proxies, r)
+		warnUntrustedProxy(cfg.proxies, r, c)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientContextKey{}, c)))
+	})
+}
+
+func requestClient(r *http.Request) client {
+	if c, ok := r.Context().Value(clientContextKey{}).(client); ok {
+		return c
+	}
+
+	return resolveClient(nil, r)
+}
+
+// remoteHost returns the client address from realIP, without the port.
+func remoteHost(r *http.Request) string {
+	addr := realIP(r)
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	}
+
+	return host
+}
+
+// clientScheme returns the scheme used by the client to reach the server.
+func clientScheme(r *http.Request) string {
+	return requestClient(r).scheme
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveClient(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		addr    string
+		scheme  string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5:1234", "http"},
+		{"untrusted peer", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https"}, "203.0.113.5:1234", "http"},
+		{"one proxy", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https"}, "198.51.100.7", "https"},
+		{"spoofed address", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7"}, "198.51.100.7", "http"},
+		{"two proxies", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2", "X-Forwarded-Proto": "https, http"}, "198.51.100.7", "https"},
+		{"spoofed scheme", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Forwarded-Proto": "https, http"}, "198.51.100.7", "http"},
+		{"spoofed hop and scheme", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7", "X-Forwarded-Proto": "http, https"}, "198.51.100.7", "https"},
+		{"single scheme", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2", "X-Forwarded-Proto": "https"}, "198.51.100.7", "https"},
+		{"real ip", "192.0.2.1:80", map[string]string{"X-Real-IP": "198.51.100.7", "X-Forwarded-Proto": "http, https"}, "198.51.100.7", "https"},
+		{"forwarded", "10.0.0.1:80", map[string]string{"Forwarded": `for=1.2.3.4;proto=http, for="198.51.100.7:4711";proto=https`}, "198.51.100.7:4711", "https"},
+		{"forwarded ipv6", "10.0.0.1:80", map[string]string{"Forwarded": `for="[2001:db8::1]"`}, "[2001:db8::1]", "http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			c := resolveClient(proxies, r)
+			if c.String() != tt.addr || c.scheme != tt.scheme {
+				t.Errorf("resolveClient = %s %s, want %s %s", c.String(), c.scheme, tt.addr, tt.scheme)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiterSweepInterval controls how often idle buckets are discarded.
+const rateLimiterSweepInterval = 5 * time.Minute
+
+// rateLimit allows a burst of n events, refilling at n per interval. The
+// zero value is unlimited.
+type rateLimit struct {
+	n        int
+	interval time.Duration
+}
+
+// parseRateLimit parses a limit of the form n/interval, e.g. 10/1m, or 0 to
+// disable it.
+func parseRateLimit(s string) (rateLimit, error) {
+	if s == "0" || s == "" {
+		return rateLimit{}, nil
+	}
+
+	count, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return rateLimit{}, fmt.Errorf("invalid rate limit (must be n/interval, e.g. 10/1m, or 0): %q", s)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit (must be n/interval, e.g. 10/1m, or 0): %q", s)
+	}
+
+	interval, err := time.ParseDuration(per)
+	if err != nil || interval <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit (must be n/interval, e.g. 10/1m, or 0): %q", s)
+	}
+
+	return rateLimit{n: n, interval: interval}, nil
+}
+
+func (l rateLimit) enabled() bool {
+	return l.n > 0
+}
+
+// tokenBucket is not safe for concurrent use.
+type tokenBucket struct {
+	limit  rateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit rateLimit) *tokenBucket {
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.n),
+		last:   time.Now(),
+	}
+}
+
+// setLimit changes the limit of a bucket, keeping the tokens it has left up
+// to the new burst size. A bucket which was unlimited starts full.
+func (b *tokenBucket) setLimit(limit rateLimit) {
+	if limit == b.limit {
+		return
+	}
+
+	if b.limit.enabled() {
+		b.tokens = math.Min(b.tokens, float64(limit.n))
+	} else {
+		b.tokens = float64(limit.n)
+	}
+	b.limit = limit
+}
+
+// take consumes a token if one is available, and otherwise returns how long
+// until the next one will be.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	if !b.limit.enabled() {
+		return true, 0
+	}
+
+	perToken := b.limit.interval.Seconds() / float64(b.limit.n)
+
+	b.tokens = math.Min(float64(b.limit.n), b.tokens+now.Sub(b.last).Seconds()/perToken)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) * perToken * float64(time.Second))
+}
+
+// rateLimiter keeps a token bucket per key, such as a client address. The
+// limit is looked up on every event, so that it can be changed at runtime.
+type rateLimiter struct {
+	name  string
+	limit func() rateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(name string, limit func() rateLimit) *rateLimiter {
+	return &rateLimiter{
+		name:      name,
+		limit:     limit,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether an event for key is within the limit, and if not,
+// how long the caller should wait before retrying. Rejections are counted
+// in metrics.
+func (rl *rateLimiter) allow(key string) (bool, time.Duration) {
+	if rl == nil {
+		return true, 0
+	}
+
+	limit := rl.limit()
+	if !limit.enabled() {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > rateLimiterSweepInterval {
+		rl.sweepLocked(now, limit)
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = newTokenBucket(limit)
+		rl.buckets[key] = b
+	}
+	b.setLimit(limit)
+
+	allowed, wait := b.take(now)
+	if !allowed {
+		metrics.rateLimited.inc(rl.name)
+	}
+
+	return allowed, wait
+}
+
+// sweepLocked discards buckets which have refilled completely, since they
+// are indistinguishable from new ones.
+func (rl *rateLimiter) sweepLocked(now time.Time, limit rateLimit) {
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= limit.interval {
+			delete(rl.buckets, key)
+		}
+	}
+
+	rl.lastSweep = now
+}
+
+// rateLimitError is returned when an action is refused by a rate limit.
+type rateLimitError struct {
+	wait time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return "Too many requests. Please wait a moment and try again."
+}
+
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(wait.Seconds()))))
+}
+
+// serveTooManyRequests responds with 429 and a Retry-After header.
+func serveTooManyRequests(cfg *Config, w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Retry-After", retryAfter(wait))
+	securityHeaders(cfg, w)
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	_, _ = io.WriteString(w, newPage(cfg, "Too Many Requests", "Too many requests. Please wait a moment and try again."))
+}
+
+// rateLimitHandler limits the rate of HTTP requests per client address.
+// Health checks are exempt, since probes often share an address.
+func rateLimitHandler(cfg *Config, next http.Handler) http.Handler {
+	rl := newRateLimiter("http", func() rateLimit { return cfg.limits().HTTP })
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, cfg.prefix+"/healthz") {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if ok, wait := rl.allow(remoteHost(r)); !ok {
+			slog.Debug("Rate limited request", "ip", realIP(r), "path", r.URL.Path)
+
+			serveTooManyRequests(cfg, w, wait)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
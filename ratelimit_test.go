@@ -0,0 +1,143 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rateLimit
+		wantErr bool
+	}{
+		{"0", rateLimit{}, false},
+		{"", rateLimit{}, false},
+		{"10/1m", rateLimit{n: 10, interval: time.Minute}, false},
+		{"1/500ms", rateLimit{n: 1, interval: 500 * time.Millisecond}, false},
+		{"10", rateLimit{}, true},
+		{"0/1m", rateLimit{}, true},
+		{"-1/1m", rateLimit{}, true},
+		{"x/1m", rateLimit{}, true},
+		{"10/0s", rateLimit{}, true},
+		{"10/-1m", rateLimit{}, true},
+		{"10/minute", rateLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRateLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRateLimit(%q) = %+v, %v, want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name  string
+		limit rateLimit
+		takes []time.Duration // offsets from start
+		want  []bool
+	}{
+		{"unlimited", rateLimit{}, []time.Duration{0, 0, 0, 0}, []bool{true, true, true, true}},
+		{"burst", rateLimit{n: 3, interval: 3 * time.Second}, []time.Duration{0, 0, 0, 0}, []bool{true, true, true, false}},
+		{"refill", rateLimit{n: 2, interval: 2 * time.Second}, []time.Duration{0, 0, 0, time.Second, time.Second}, []bool{true, true, false, true, false}},
+		{"full after idle", rateLimit{n: 2, interval: time.Second}, []time.Duration{0, 0, time.Hour, time.Hour, time.Hour}, []bool{true, true, true, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.limit)
+			b.last = start
+
+			for i, offset := range tt.takes {
+				if got, _ := b.take(start.Add(offset)); got != tt.want[i] {
+					t.Fatalf("take %d at %s = %v, want %v", i, offset, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	start := time.Now()
+
+	b := newTokenBucket(rateLimit{n: 1, interval: 10 * time.Second})
+	b.last = start
+
+	if ok, _ := b.take(start); !ok {
+		t.Fatal("first take refused")
+	}
+
+	ok, wait := b.take(start.Add(4 * time.Second))
+	if ok {
+		t.Fatal("second take allowed")
+	}
+	if wait < 5900*time.Millisecond || wait > 6100*time.Millisecond {
+		t.Errorf("wait = %s, want about 6s", wait)
+	}
+}
+
+func TestTokenBucketSetLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   rateLimit
+		taken  int
+		to     rateLimit
+		tokens float64
+	}{
+		{"unchanged", rateLimit{n: 5, interval: time.Minute}, 2, rateLimit{n: 5, interval: time.Minute}, 3},
+		{"lowered", rateLimit{n: 5, interval: time.Minute}, 1, rateLimit{n: 2, interval: time.Minute}, 2},
+		{"raised", rateLimit{n: 2, interval: time.Minute}, 1, rateLimit{n: 5, interval: time.Minute}, 1},
+		{"was unlimited", rateLimit{}, 3, rateLimit{n: 4, interval: time.Minute}, 4},
+		{"now unlimited", rateLimit{n: 2, interval: time.Minute}, 2, rateLimit{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+
+			b := newTokenBucket(tt.from)
+			b.last = start
+			for range tt.taken {
+				b.take(start)
+			}
+
+			b.setLimit(tt.to)
+			if b.tokens != tt.tokens {
+				t.Errorf("tokens = %v, want %v", b.tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limit := rateLimit{n: 2, interval: time.Hour}
+	rl := newRateLimiter("test", func() rateLimit { return limit })
+
+	for i, want := range []bool{true, true, false} {
+		if got, _ := rl.allow("a"); got != want {
+			t.Fatalf("allow a #%d = %v, want %v", i, got, want)
+		}
+	}
+
+	if ok, _ := rl.allow("b"); !ok {
+		t.Error("separate key was limited")
+	}
+
+	limit = rateLimit{}
+	if ok, _ := rl.allow("a"); !ok {
+		t.Error("limited after the limit was disabled")
+	}
+
+	var nilLimiter *rateLimiter
+	if ok, _ := nilLimiter.allow("a"); !ok {
+		t.Error("nil limiter refused")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// storeCheckInterval is how often health checks may test that the store is
+// still writable. Between checks, they report the last result.
+const storeCheckInterval = time.Minute
+
+// storeData is everything persisted across restarts.
+type storeData struct {
+	Bans []*Ban `json:"bans"`
+}
+
+// Store persists state to a JSON file, which is replaced atomically on
+// every save. A nil *Store is valid and persists nothing.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	err     error     // result of the last save or check
+	checked time.Time // time of the last save or check
+}
+
+func openStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create store directory: %w", err)
+	}
+
+	s := &Store{path: path, checked: time.Now()}
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// checkWritable verifies that a save could replace the store, by creating
+// and removing a file alongside it.
+func (s *Store) checkWritable() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("store is not writable: %w", err)
+	}
+
+	tmp.Close()
+
+	return os.Remove(tmp.Name())
+}
+
+// load reads the store, returning empty data if it does not exist yet.
+func (s *Store) load() (*storeData, error) {
+	data := &storeData{}
+
+	if s == nil {
+		return data, nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return data, nil
+	case err != nil:
+		return nil, fmt.Errorf("unable to read store: %w", err)
+	}
+
+	if err := json.Unmarshal(b, data); err != nil {
+		return nil, fmt.Errorf("unable to parse store %q: %w", s.path, err)
+	}
+
+	return data, nil
+}
+
+func (s *Store) save(data *storeData) error {
+	if s == nil {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = writeFileAtomic(s.path, b)
+	s.checked = time.Now()
+
+	return s.err
+}
+
+// status reports the state of the store for health checks, including
+// whether it can still be written to, so that a problem is noticed before a
+// save fails. Unless it was saved recently, writability is checked at most
+// once per storeCheckInterval, so that frequent probes do not touch disk.
+func (s *Store) status() (string, error) {
+	if s == nil {
+		return "disabled", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checked) >= storeCheckInterval {
+		s.err = s.checkWritable()
+		s.checked = time.Now()
+	}
+
+	if s.err != nil {
+		return "error", s.err
+	}
+
+	return "ok", nil
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
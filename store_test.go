@@ -0,0 +1,51 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreStatus(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+
+	s, err := openStore(filepath.Join(dir, "store.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The result of the check at startup is reported until it is due again.
+	if status, err := s.status(); status != "ok" || err != nil {
+		t.Errorf("status = %q, %v before the next check, want ok", status, err)
+	}
+
+	s.checked = time.Now().Add(-storeCheckInterval)
+	if status, err := s.status(); status != "error" || err == nil {
+		t.Errorf("status = %q, %v with the directory gone, want an error", status, err)
+	}
+
+	// A successful save clears the error straight away.
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.save(&storeData{}); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := s.status(); status != "ok" || err != nil {
+		t.Errorf("status = %q, %v after a save, want ok", status, err)
+	}
+
+	var nilStore *Store
+	if status, _ := nilStore.status(); status != "disabled" {
+		t.Errorf("status of a nil store = %q, want disabled", status)
+	}
+}
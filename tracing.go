@@ -0,0 +1,481 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	statusCodeError = 2
+
+	traceBatchSize     = 512
+	traceFlushInterval = 5 * time.Second
+	traceQueueSize     = 4096
+)
+
+type spanContextKey struct{}
+
+// unsampledKey marks a context whose trace was not sampled, so that spans
+// started from it are not recorded either.
+type unsampledKey struct{}
+
+func withUnsampled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unsampledKey{}, true)
+}
+
+// Span is a single timed operation, exported to an OTLP/HTTP collector when
+// it ends. A nil *Span is valid and records nothing, so callers need not
+// check whether tracing is enabled.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+
+	mu         sync.Mutex
+	attributes map[string]any
+	statusCode int
+	statusMsg  string
+	ended      bool
+}
+
+func (s *Span) SetAttributes(kv ...any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		s.attributes[key] = kv[i+1]
+	}
+}
+
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.statusCode = statusCodeError
+	s.statusMsg = msg
+	s.mu.Unlock()
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+
+		return
+	}
+	s.ended = true
+	s.mu.Unlock()
+
+	s.tracer.export(s, time.Now())
+}
+
+// Tracer batches finished spans and posts them to an OTLP/HTTP endpoint
+// using the JSON encoding.
+type Tracer struct {
+	endpoint    string
+	service     string
+	sampleRatio float64
+	client      *http.Client
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan otlpSpan
+	done   chan struct{}
+}
+
+var tracer *Tracer
+
+func newTracer(cfg *Config) *Tracer {
+	endpoint := strings.TrimSuffix(cfg.traceEndpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+
+	t := &Tracer{
+		endpoint:    endpoint,
+		service:     cfg.traceServiceName,
+		sampleRatio: cfg.traceSampleRatio,
+		client:      &http.Client{Timeout: timeout},
+		queue:       make(chan otlpSpan, traceQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+// Start begins a span as a child of any span in ctx. Root spans are sampled
+// according to the configured ratio, and children follow their parent,
+// including a parent which was not sampled.
+func (t *Tracer) Start(ctx context.Context, name string, kind int, kv ...any) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	if ctx.Value(unsampledKey{}) != nil {
+		return ctx, nil
+	}
+
+	parent, _ := ctx.Value(spanContextKey{}).(*Span)
+
+	var s *Span
+
+	switch {
+	case parent != nil:
+		s = t.newSpan(name, kind)
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	default:
+		s = t.newSpan(name, kind)
+		_, _ = rand.Read(s.traceID[:])
+		if !t.sampled(s.traceID) {
+			return withUnsampled(ctx), nil
+		}
+	}
+
+	s.SetAttributes(kv...)
+
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// StartRemote begins a server span, continuing a W3C traceparent from the
+// request if one is present and sampled.
+func (t *Tracer) StartRemote(r *http.Request, name string, kv ...any) (context.Context, *Span) {
+	if t == nil {
+		return r.Context(), nil
+	}
+
+	traceID, parentID, sampled, ok := parseTraceParent(r.Header.Get("traceparent"))
+	if !ok {
+		return t.Start(r.Context(), name, spanKindServer, kv...)
+	}
+	if !sampled {
+		return withUnsampled(r.Context()), nil
+	}
+
+	s := t.newSpan(name, spanKindServer)
+	s.traceID = traceID
+	s.parentID = parentID
+	s.SetAttributes(kv...)
+
+	return context.WithValue(r.Context(), spanContextKey{}, s), s
+}
+
+func (t *Tracer) newSpan(name string, kind int) *Span {
+	s := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]any),
+	}
+	_, _ = rand.Read(s.spanID[:])
+
+	return s
+}
+
+func (t *Tracer) sampled(traceID [16]byte) bool {
+	switch {
+	case t.sampleRatio >= 1:
+		return true
+	case t.sampleRatio <= 0:
+		return false
+	}
+
+	n := binary.BigEndian.Uint64(traceID[8:]) >> 11
+
+	return float64(n)/float64(uint64(1)<<53) < t.sampleRatio
+}
+
+func parseTraceParent(header string) (traceID [16]byte, spanID [8]byte, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, spanID, false, false
+	}
+
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, spanID, false, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || spanID == [8]byte{} {
+		return traceID, spanID, false, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return traceID, spanID, false, false
+	}
+
+	return traceID, spanID, flags&1 == 1, true
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpAttr(key string, v any) otlpAttribute {
+	var val otlpValue
+
+	switch x := v.(type) {
+	case string:
+		val.StringValue = &x
+	case bool:
+		val.BoolValue = &x
+	case int:
+		s := strconv.Itoa(x)
+		val.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		val.IntValue = &s
+	case float64:
+		val.DoubleValue = &x
+	default:
+		s := fmt.Sprint(x)
+		val.StringValue = &s
+	}
+
+	return otlpAttribute{Key: key, Value: val}
+}
+
+func (t *Tracer) export(s *Span, end time.Time) {
+	s.mu.Lock()
+	out := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Status:            otlpStatus{Code: s.statusCode, Message: s.statusMsg},
+	}
+	for _, key := range sortedKeys(s.attributes) {
+		out.Attributes = append(out.Attributes, otlpAttr(key, s.attributes[key]))
+	}
+	s.mu.Unlock()
+
+	if s.parentID != [8]byte{} {
+		out.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return
+	}
+
+	select {
+	case t.queue <- out:
+	default:
+		slog.Debug("Trace queue full, dropping span", "name", s.name)
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpSpan, 0, traceBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.post(batch); err != nil {
+			slog.Warn("Failed to export spans", "endpoint", t.endpoint, "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s, ok := <-t.queue:
+			if !ok {
+				flush()
+
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= traceBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (t *Tracer) post(spans []otlpSpan) error {
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{
+					otlpAttr("service.name", t.service),
+					otlpAttr("service.version", releaseVersion),
+				},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "partybox", Version: releaseVersion},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Shutdown flushes any queued spans, waiting until ctx is done at most.
+func (t *Tracer) Shutdown(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+}
+
+func traceHandler(mux *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routePattern(mux, r)
+
+		ctx, span := tracer.StartRemote(r, r.Method+" "+route,
+			"http.request.method", r.Method,
+			"http.route", route,
+			"url.path", r.URL.Path,
+			"client.address", remoteHost(r),
+			"user_agent.original", r.UserAgent(),
+		)
+		if span == nil {
+			next.ServeHTTP(w, r.WithContext(ctx))
+
+			return
+		}
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes("http.response.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(status))
+		}
+	})
+}
@@ -0,0 +1,274 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// collector is an in-process stand-in for an OTLP/HTTP trace collector.
+type collector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []otlpRequest
+}
+
+func newCollector(t *testing.T) *collector {
+	t.Helper()
+
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		c.requests = append(c.requests, req)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *collector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var spans []otlpSpan
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+
+	return spans
+}
+
+func newTestTracer(t *testing.T, endpoint string, ratio float64) *Tracer {
+	t.Helper()
+
+	return newTracer(&Config{
+		traceEndpoint:    endpoint,
+		traceServiceName: "partybox-test",
+		traceSampleRatio: ratio,
+	})
+}
+
+func shutdownTracer(t *testing.T, tr *Tracer) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tr.Shutdown(ctx)
+}
+
+func spanByName(spans []otlpSpan, name string) (otlpSpan, bool) {
+	for _, s := range spans {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return otlpSpan{}, false
+}
+
+func TestTracerExportsSpans(t *testing.T) {
+	c := newCollector(t)
+	tr := newTestTracer(t, c.URL, 1)
+
+	ctx, root := tr.Start(context.Background(), "root", spanKindServer, "game.type", "celebrity")
+	_, child := tr.Start(ctx, "child", spanKindInternal)
+	child.SetError("boom")
+	child.End()
+	root.End()
+
+	shutdownTracer(t, tr)
+
+	c.mu.Lock()
+	if len(c.requests) == 0 {
+		c.mu.Unlock()
+		t.Fatal("collector received no requests")
+	}
+	attrs := c.requests[0].ResourceSpans[0].Resource.Attributes
+	c.mu.Unlock()
+
+	found := false
+	for _, a := range attrs {
+		if a.Key == "service.name" && a.Value.StringValue != nil && *a.Value.StringValue == "partybox-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("resource attributes %+v lack service.name", attrs)
+	}
+
+	spans := c.spans()
+
+	r, ok := spanByName(spans, "root")
+	if !ok {
+		t.Fatalf("root span not exported: %+v", spans)
+	}
+	ch, ok := spanByName(spans, "child")
+	if !ok {
+		t.Fatalf("child span not exported: %+v", spans)
+	}
+
+	if r.ParentSpanID != "" {
+		t.Errorf("root span has parent %q", r.ParentSpanID)
+	}
+	if r.Kind != spanKindServer {
+		t.Errorf("root span kind = %d, want %d", r.Kind, spanKindServer)
+	}
+	if ch.TraceID != r.TraceID {
+		t.Errorf("child trace ID = %s, want %s", ch.TraceID, r.TraceID)
+	}
+	if ch.ParentSpanID != r.SpanID {
+		t.Errorf("child parent = %s, want %s", ch.ParentSpanID, r.SpanID)
+	}
+	if ch.Status.Code != statusCodeError || ch.Status.Message != "boom" {
+		t.Errorf("child status = %+v, want error boom", ch.Status)
+	}
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name    string
+		header  string
+		sampled bool
+		trace   string // expected trace ID, if continued
+		parent  string // expected parent span ID, if continued
+	}{
+		{"sampled", "00-" + traceID + "-" + parentID + "-01", true, traceID, parentID},
+		{"not sampled", "00-" + traceID + "-" + parentID + "-00", false, "", ""},
+		{"invalid", "00-xyz-" + parentID + "-01", true, "", ""},
+		{"missing", "", true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCollector(t)
+			tr := newTestTracer(t, c.URL, 1)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("traceparent", tt.header)
+			}
+
+			ctx, server := tr.StartRemote(r, "server")
+			_, child := tr.Start(ctx, "child", spanKindInternal)
+
+			if (server != nil) != tt.sampled || (child != nil) != tt.sampled {
+				t.Fatalf("server span %v, child span %v, want sampled %v", server != nil, child != nil, tt.sampled)
+			}
+
+			child.End()
+			server.End()
+			shutdownTracer(t, tr)
+
+			spans := c.spans()
+			if !tt.sampled {
+				if len(spans) != 0 {
+					t.Fatalf("exported %d spans from an unsampled trace", len(spans))
+				}
+				return
+			}
+
+			s, ok := spanByName(spans, "server")
+			if !ok {
+				t.Fatalf("server span not exported: %+v", spans)
+			}
+			if tt.trace != "" && s.TraceID != tt.trace {
+				t.Errorf("trace ID = %s, want %s", s.TraceID, tt.trace)
+			}
+			if s.ParentSpanID != tt.parent {
+				t.Errorf("parent = %q, want %q", s.ParentSpanID, tt.parent)
+			}
+
+			ch, ok := spanByName(spans, "child")
+			if !ok || ch.ParentSpanID != s.SpanID || ch.TraceID != s.TraceID {
+				t.Errorf("child %+v is not linked to server span %+v", ch, s)
+			}
+		})
+	}
+}
+
+func TestTracerUnsampledRoot(t *testing.T) {
+	c := newCollector(t)
+	tr := newTestTracer(t, c.URL, 0)
+
+	ctx, root := tr.Start(context.Background(), "root", spanKindServer)
+	if root != nil {
+		t.Fatal("root span sampled with a ratio of 0")
+	}
+
+	if _, child := tr.Start(ctx, "child", spanKindInternal); child != nil {
+		t.Fatal("child of an unsampled root was sampled")
+	}
+
+	shutdownTracer(t, tr)
+
+	if spans := c.spans(); len(spans) != 0 {
+		t.Fatalf("exported %d spans from an unsampled trace", len(spans))
+	}
+}
+
+// TestTraceHandlerUnsampled checks that work started by a handler, such as a
+// WebSocket session, follows a caller's decision not to sample.
+func TestTraceHandlerUnsampled(t *testing.T) {
+	c := newCollector(t)
+	tr := newTestTracer(t, c.URL, 1)
+
+	saved := tracer
+	tracer = tr
+	t.Cleanup(func() { tracer = saved })
+
+	var sampled bool
+
+	mux := httprouter.New()
+	mux.GET("/ws", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		_, span := tracer.Start(r.Context(), "websocket.session", spanKindInternal)
+		sampled = span != nil
+		span.End()
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	traceHandler(mux, mux).ServeHTTP(httptest.NewRecorder(), r)
+
+	shutdownTracer(t, tr)
+
+	if sampled {
+		t.Error("session span sampled although the caller's trace was not")
+	}
+	if spans := c.spans(); len(spans) != 0 {
+		t.Errorf("exported %d spans from an unsampled trace", len(spans))
+	}
+}
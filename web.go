@@ -5,14 +5,15 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"bufio"
 	"context"
-	"errors"
-	"fmt"
+	"crypto/rand"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,7 +21,6 @@ import (
 )
 
 const (
-	logDate string        = `2006-01-02T15:04:05.000-07:00`
 	timeout time.Duration = 10 * time.Second
 )
 
@@ -38,24 +38,104 @@ func securityHeaders(cfg *Config, w http.ResponseWriter) {
 	}
 }
 
-func realIP(r *http.Request) string {
-	host, port, _ := net.SplitHostPort(r.RemoteAddr)
-	if ip := r.Header.Get("CF-Connecting-IP"); ip != "" {
-		if net.ParseIP(ip) != nil {
-			host = ip
-		}
-	} else if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		if net.ParseIP(ip) != nil {
-			host = ip
-		}
+// statusRecorder captures the status code and response size for logging
+// and metrics middleware.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.written += int64(n)
+
+	return n, err
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack allows WebSocket upgrades through the recorder.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+
+	return http.NewResponseController(s.ResponseWriter).Hijack()
+}
+
+func (s *statusRecorder) Flush() {
+	_ = http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// normalizeOrigin returns the scheme and host of an origin, lowercased and
+// without any default port.
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return "", false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+
+	if port := u.Port(); (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+
+	return scheme + "://" + host, true
+}
+
+// originAllowed reports whether a browser request came from a page served
+// by this server, or from one of --allowed-origins. Requests without an
+// Origin header are not made by browsers, and are always allowed.
+func originAllowed(cfg *Config, r *http.Request) bool {
+	header := r.Header.Get("Origin")
+	if header == "" {
+		return true
 	}
-	if net.ParseIP(host) != nil && strings.Contains(host, ":") {
-		host = "[" + host + "]"
+
+	origin, ok := normalizeOrigin(header)
+	if !ok {
+		return false
+	}
+
+	if cfg.baseURL != nil {
+		if self, ok := normalizeOrigin(cfg.baseURL.String()); ok && origin == self {
+			return true
+		}
+	} else if _, host, _ := strings.Cut(origin, "://"); strings.EqualFold(host, r.Host) {
+		return true
 	}
-	if port != "" {
-		return host + ":" + port
+
+	for _, allowed := range cfg.allowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+		if a, ok := normalizeOrigin(allowed); ok && a == origin {
+			return true
+		}
 	}
-	return host
+
+	return false
+}
+
+// realIP returns the address of the client, honoring forwarded headers only
+// when set by a trusted proxy; see resolveClient.
+func realIP(r *http.Request) string {
+	return requestClient(r).String()
 }
 
 func serveVersion(cfg *Config, errs chan<- error) httprouter.Handle {
@@ -73,10 +153,10 @@ func serveVersion(cfg *Config, errs chan<- error) httprouter.Handle {
 			return
 		}
 
-		logf(cfg, "SERVE: Version page (%s) to %s in %s",
-			humanReadableSize(int64(written)),
-			realIP(r),
-			time.Since(startTime).Round(time.Microsecond),
+		slog.Info("Served version page",
+			"size", humanReadableSize(int64(written)),
+			"ip", realIP(r),
+			"duration", time.Since(startTime).Round(time.Microsecond),
 		)
 	}
 }
@@ -92,28 +172,94 @@ func ServePage(ctx context.Context, cfg *Config, args []string) error {
 		}
 	}
 
-	logf(cfg, "START: partybox v%s", releaseVersion)
+	logger, err := newLogger(cfg, os.Stdout)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	slog.Info("Starting partybox", "version", releaseVersion)
+
+	// Used to sign cookies if no secret is configured.
+	cfg.cookieFallbackKey = make([]byte, 32)
+	if _, err := rand.Read(cfg.cookieFallbackKey); err != nil {
+		return err
+	}
+	if len(cfg.cookieSecrets) == 0 {
+		slog.Warn("No cookie secret set, player cookies will be invalidated on restart")
+	}
+
+	cfg.filter, err = newContentFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.configLoader != nil {
+		cfg.configLoader.watch(ctx, cfg)
+	}
+
+	var store *Store
+	if cfg.store != "" {
+		store, err = openStore(cfg.store)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("Using store", "path", cfg.store)
+	}
+
+	if err := bans.load(store); err != nil {
+		return err
+	}
+	go bans.pruneLoop(ctx)
 
 	mux := httprouter.New()
 
+	var handler http.Handler = instrumentHandler(mux, mux)
+
+	handler = rateLimitHandler(cfg, handler)
+
+	if cfg.traceEndpoint != "" {
+		tracer = newTracer(cfg)
+		handler = traceHandler(mux, handler)
+		slog.Info("Exporting traces", "endpoint", tracer.endpoint, "sample_ratio", cfg.traceSampleRatio)
+	}
+
+	if cfg.accessLog != "" {
+		al, err := newAccessLogger(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		handler = al.handler(handler)
+	}
+
+	handler = clientHandler(cfg, handler)
+
 	srv := &http.Server{
-		Addr:              net.JoinHostPort(cfg.bind, strconv.Itoa(cfg.port)),
-		Handler:           mux,
+		Handler:           handler,
 		IdleTimeout:       10 * time.Minute,
 		ReadTimeout:       timeout,
 		ReadHeaderTimeout: timeout,
 		WriteTimeout:      timeout,
 	}
 
+	if cfg.tlsKey != "" && cfg.tlsCert != "" {
+		srv.TLSConfig, err = newTLSConfig(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
 	mux.PanicHandler = func(w http.ResponseWriter, r *http.Request, i any) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		securityHeaders(cfg, w)
 		w.WriteHeader(http.StatusInternalServerError)
 
-		io.WriteString(w, newPage("Server Error", "An error has occurred. Please try again."))
+		io.WriteString(w, newPage(cfg, "Server Error", "An error has occurred. Please try again."))
 	}
 
 	errs := make(chan error, 64)
+	go drainErrors(errs)
 
 	cfg.prefix = strings.TrimSuffix(cfg.prefix, "/")
 
@@ -123,36 +269,83 @@ func ServePage(ctx context.Context, cfg *Config, args []string) error {
 
 	mux.GET(cfg.prefix+"/favicon.webp", serveFavicons(cfg, errs))
 
+	st := newServerStatus(cfg)
+	st.store = store
+
 	mux.GET(cfg.prefix+"/healthz", serveHealthCheck(cfg, errs))
 
+	mux.GET(cfg.prefix+"/healthz/live", serveLiveness(cfg, st, errs))
+
+	mux.GET(cfg.prefix+"/healthz/ready", serveReadiness(cfg, st, errs))
+
 	mux.GET(cfg.prefix+"/robots.txt", serveRobots(cfg, errs))
 
 	mux.GET(cfg.prefix+"/version", serveVersion(cfg, errs))
 
-	if cfg.profile {
-		registerProfileHandlers(cfg, mux)
+	st.games = append(st.games, registerCelebrityGame(cfg, "/celebrity", mux))
+
+	if cfg.metrics && cfg.opsAddr == "" {
+		mux.GET(cfg.prefix+"/metrics", requireClientCertHandle(cfg, serveMetrics(cfg, st, errs)))
+	}
+
+	servers := []*http.Server{srv}
+
+	ln, addr, err := listen(cfg)
+	if err != nil {
+		return err
+	}
+
+	if ln.Addr().Network() == "unix" {
+		slog.Info("Listening", "socket", addr, "scheme", cfg.scheme(), "prefix", cfg.prefix+"/")
+	} else {
+		slog.Info("Listening", "url", cfg.scheme()+"://"+addr+cfg.prefix+"/")
 	}
 
-	registerCelebrityGame(cfg, "/celebrity", mux)
+	serve(srv, ln, "main")
+
+	if cfg.opsAddr != "" {
+		opsSrv := newOpsServer(cfg, st, errs)
+		opsSrv.TLSConfig = srv.TLSConfig
 
-	go func() {
-		var err error
-		if cfg.tlsKey != "" && cfg.tlsCert != "" {
-			logf(cfg, "SERVE: Listening on %s://%s%s/", cfg.scheme(), srv.Addr, cfg.prefix)
-			err = srv.ListenAndServeTLS(cfg.tlsCert, cfg.tlsKey)
-		} else {
-			logf(cfg, "SERVE: Listening on %s://%s%s/", cfg.scheme(), srv.Addr, cfg.prefix)
-			err = srv.ListenAndServe()
+		opsLn, err := listenAddr(cfg, cfg.opsAddr)
+		if err != nil {
+			return err
 		}
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			fmt.Printf("%s | ERROR: %v\n", time.Now().Format(logDate), err)
+
+		slog.Info("Listening for operations", "addr", cfg.opsAddr, "scheme", cfg.scheme())
+
+		serve(opsSrv, opsLn, "ops")
+		servers = append(servers, opsSrv)
+	}
+
+	if cfg.redirectAddr != "" {
+		redirectSrv := newRedirectServer(cfg)
+
+		redirectLn, err := listenAddr(cfg, cfg.redirectAddr)
+		if err != nil {
+			return err
 		}
-	}()
+
+		slog.Info("Redirecting to https", "addr", cfg.redirectAddr)
+
+		serve(redirectSrv, redirectLn, "redirect")
+		servers = append(servers, redirectSrv)
+	}
 
 	<-ctx.Done()
+
+	st.draining.Store(true)
+	if cfg.drainTimeout > 0 {
+		slog.Info("Draining before shutdown", "timeout", cfg.drainTimeout)
+		st.drain(cfg.drainTimeout)
+	}
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_ = srv.Shutdown(shutdownCtx)
+	for _, s := range servers {
+		_ = s.Shutdown(shutdownCtx)
+	}
+	tracer.Shutdown(shutdownCtx)
 
 	return nil
 }